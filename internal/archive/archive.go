@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Format is an archive format.
+type Format string
+
+// Supported archive formats.
+const (
+	FormatZip   Format = "zip"
+	FormatTarGz Format = "tar.gz"
+)
+
+// ParseFormat returns the archive format of the specified name. An empty name is ZIP.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatZip, "":
+		return FormatZip, nil
+	case FormatTarGz:
+		return FormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q", name)
+	}
+}
+
+// Extension returns the file extension of the format including the leading dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatTarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+// EntryName returns the name of the entry for the file at the relative path rel in the root folder of an archive.
+// It reports false if the file could be extracted outside of the root folder (zip slip), e.g. for S3 keys with
+// ".." segments. Backslashes are also treated as separators for the check since some extractors do.
+func EntryName(root, rel string) (string, bool) {
+	if root == "" || root == "." || root == ".." || strings.ContainsAny(root, `/\`) {
+		return "", false
+	}
+	if strings.Contains("/"+strings.ReplaceAll(rel, `\`, "/")+"/", "/../") {
+		return "", false
+	}
+	name := path.Join(root, rel)
+	if !strings.HasPrefix(name, root+"/") {
+		return "", false
+	}
+	return name, true
+}
+
+// Writer writes files to an archive as a stream.
+type Writer interface {
+	// Add writes a file of the specified size read from r.
+	Add(name string, size int64, modTime time.Time, r io.Reader) error
+	// Close finishes the archive. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a Writer of the specified format that writes to w.
+func NewWriter(format Format, w io.Writer) Writer {
+	if format == FormatTarGz {
+		gw := gzip.NewWriter(w)
+		return &tarGzWriter{gw: gw, tw: tar.NewWriter(gw)}
+	}
+	return &zipWriter{zw: zip.NewWriter(w)}
+}
+
+// zipWriter is a Writer of ZIP archives.
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+// Add writes a deflated file to the ZIP archive.
+func (w *zipWriter) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	fw, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %q to zip: %w", name, err)
+	}
+	if _, err := io.Copy(fw, r); err != nil {
+		return fmt.Errorf("failed to write %q to zip: %w", name, err)
+	}
+	return nil
+}
+
+// Close finishes the ZIP archive.
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// tarGzWriter is a Writer of gzip-compressed tar archives.
+type tarGzWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+// Add writes a regular file to the tar archive. r must provide exactly size bytes.
+func (w *tarGzWriter) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %q to tar: %w", name, err)
+	}
+	if _, err := io.Copy(w.tw, r); err != nil {
+		return fmt.Errorf("failed to write %q to tar: %w", name, err)
+	}
+	return nil
+}
+
+// Close finishes the tar archive and the gzip stream.
+func (w *tarGzWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gw.Close()
+}
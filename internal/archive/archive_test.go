@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseFormat tests the ParseFormat function with various format names.
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Format
+		expectedErr string
+	}{
+		{name: "デフォルト", input: "", expected: FormatZip},
+		{name: "ZIP", input: "zip", expected: FormatZip},
+		{name: "tar.gz", input: "tar.gz", expected: FormatTarGz},
+		{name: "未対応の形式", input: "rar", expectedErr: "unsupported archive format \"rar\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseFormat(tt.input)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestEntryName tests that entry names stay in the root folder of the archive
+func TestEntryName(t *testing.T) {
+	tests := []struct {
+		name       string
+		root       string
+		rel        string
+		expected   string
+		expectedOK bool
+	}{
+		{name: "ファイル", root: "logs", rel: "2025/app.log", expected: "logs/2025/app.log", expectedOK: true},
+		{name: "正規化", root: "logs", rel: "./a//b.txt", expected: "logs/a/b.txt", expectedOK: true},
+		{name: "ドットを含む名前", root: "logs", rel: "a..b/..c", expected: "logs/a..b/..c", expectedOK: true},
+		{name: "親フォルダへの脱出", root: "logs", rel: "../evil"},
+		{name: "途中の親フォルダ", root: "logs", rel: "a/../../evil"},
+		{name: "フォルダ内に留まる親フォルダも拒否", root: "logs", rel: "a/../b"},
+		{name: "バックスラッシュでの脱出", root: "logs", rel: `..\evil`},
+		{name: "ルートそのもの", root: "logs", rel: ""},
+		{name: "不正なルート", root: "..", rel: "evil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := EntryName(tt.root, tt.rel)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestNewWriter tests that the written archives can be read back.
+func TestNewWriter(t *testing.T) {
+	modTime := time.Date(2025, 1, 26, 2, 5, 16, 0, time.UTC)
+	files := map[string]string{
+		"dir/test_0.txt":      "test file content test_0.txt\n",
+		"dir/hoge/test_2.txt": "test file content hoge/test_2.txt\n",
+	}
+	names := []string{"dir/test_0.txt", "dir/hoge/test_2.txt"}
+
+	write := func(format Format) *bytes.Buffer {
+		var buf bytes.Buffer
+		w := NewWriter(format, &buf)
+		for _, name := range names {
+			content := files[name]
+			assert.NoError(t, w.Add(name, int64(len(content)), modTime, strings.NewReader(content)))
+		}
+		assert.NoError(t, w.Close())
+		return &buf
+	}
+
+	t.Run("ZIP", func(t *testing.T) {
+		buf := write(FormatZip)
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, zr.File, len(names))
+		for i, f := range zr.File {
+			assert.Equal(t, names[i], f.Name)
+			assert.True(t, modTime.Equal(f.Modified))
+			rc, err := f.Open()
+			assert.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			assert.NoError(t, err)
+			assert.Equal(t, files[f.Name], string(content))
+		}
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		buf := write(FormatTarGz)
+		gr, err := gzip.NewReader(buf)
+		assert.NoError(t, err)
+		tr := tar.NewReader(gr)
+		for _, name := range names {
+			header, err := tr.Next()
+			assert.NoError(t, err)
+			assert.Equal(t, name, header.Name)
+			assert.True(t, modTime.Equal(header.ModTime))
+			content, err := io.ReadAll(tr)
+			assert.NoError(t, err)
+			assert.Equal(t, files[name], string(content))
+		}
+		_, err = tr.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+}
@@ -0,0 +1,333 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// zipBlockSize is the size of the reads of a ZIP archive, so that the many small reads of archive/zip
+	// become a few Range requests.
+	zipBlockSize = 256 << 10
+	// maxZipReadBytes is the maximum number of bytes read to list a ZIP archive, which bounds its central directory.
+	maxZipReadBytes = 64 << 20
+)
+
+// ErrNotFound is returned when the member is not in the archive.
+var ErrNotFound = errors.New("member not found")
+
+// ErrTooLarge is returned when the central directory of a ZIP archive or the part of a tar archive
+// before the member exceeds the limit.
+var ErrTooLarge = errors.New("archive directory too large")
+
+// Entry is a file or a folder in an archive.
+type Entry struct {
+	// Name is the path of the entry in the archive. Folders end with a slash.
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// IsZip reports whether the key is of a ZIP archive.
+func IsZip(key string) bool {
+	return strings.HasSuffix(strings.ToLower(key), ".zip")
+}
+
+// IsTar reports whether the key is of a tar archive, which may be gzip-compressed.
+func IsTar(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasSuffix(key, ".tar") || strings.HasSuffix(key, ".tar.gz") || strings.HasSuffix(key, ".tgz")
+}
+
+// entryName cleans the path of a member to a relative path. It returns "" for the root folder.
+func entryName(name string) string {
+	isDir := strings.HasSuffix(name, "/")
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if isDir && name != "" {
+		name += "/"
+	}
+	return name
+}
+
+// ListDir returns the folders and files directly in the folder dir of the archive, each sorted by name.
+// Folders that only appear in the paths of other entries are included. dir is "" or ends with a slash.
+func ListDir(entries []Entry, dir string) []Entry {
+	dirs := map[string]Entry{}
+	var files []Entry
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry.Name, dir)
+		if !ok || rest == "" {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			name := dir + rest[:i+1]
+			if _, found := dirs[name]; !found || entry.Name == name {
+				dirs[name] = Entry{Name: name, IsDir: true, ModTime: modTimeIf(entry.Name == name, entry.ModTime)}
+			}
+			continue
+		}
+		files = append(files, entry)
+	}
+
+	result := make([]Entry, 0, len(dirs)+len(files))
+	for _, entry := range dirs {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return append(result, files...)
+}
+
+// modTimeIf returns t if ok, or the zero time otherwise.
+func modTimeIf(ok bool, t time.Time) time.Time {
+	if ok {
+		return t
+	}
+	return time.Time{}
+}
+
+// ReadZipEntries lists the entries of a ZIP archive of the size from its central directory.
+// Only the end of the archive is read, in blocks of a few hundred kilobytes.
+func ReadZipEntries(r io.ReaderAt, size int64) ([]Entry, error) {
+	zr, err := zip.NewReader(newBlockReader(r, size), size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip directory: %w", err)
+	}
+	entries := make([]Entry, 0, len(zr.File))
+	for _, f := range zr.File {
+		name := entryName(f.Name)
+		if name == "" {
+			continue
+		}
+		entries = append(entries, Entry{
+			Name:    name,
+			Size:    int64(f.UncompressedSize64),
+			ModTime: f.Modified.UTC(),
+			IsDir:   strings.HasSuffix(name, "/"),
+		})
+	}
+	return entries, nil
+}
+
+// ZipMember is a file in a ZIP archive and the location of its compressed data.
+type ZipMember struct {
+	Entry
+	// Offset and CompressedSize are the range of the compressed data in the archive.
+	Offset         int64
+	CompressedSize int64
+	method         uint16
+	crc32          uint32
+}
+
+// FindZipMember finds the file of the name in a ZIP archive of the size.
+// Its data can be read with a single Range request of the archive and decompressed with Reader.
+func FindZipMember(r io.ReaderAt, size int64, name string) (*ZipMember, error) {
+	zr, err := zip.NewReader(newBlockReader(r, size), size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip directory: %w", err)
+	}
+	for _, f := range zr.File {
+		if entryName(f.Name) != name || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if f.Flags&0x1 != 0 {
+			return nil, fmt.Errorf("%q is encrypted", name)
+		}
+		if f.Method != zip.Store && f.Method != zip.Deflate {
+			return nil, fmt.Errorf("%q is compressed with unsupported method %d", name, f.Method)
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zip header of %q: %w", name, err)
+		}
+		return &ZipMember{
+			Entry:          Entry{Name: name, Size: int64(f.UncompressedSize64), ModTime: f.Modified.UTC()},
+			Offset:         offset,
+			CompressedSize: int64(f.CompressedSize64),
+			method:         f.Method,
+			crc32:          f.CRC32,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// Reader decompresses the data of the member read from compressed and verifies its size and checksum,
+// which come from the central directory and may not match the data.
+func (m *ZipMember) Reader(compressed io.Reader) io.Reader {
+	var r io.Reader = io.LimitReader(compressed, m.CompressedSize)
+	if m.method == zip.Deflate {
+		r = flate.NewReader(r)
+	}
+	return &checksumReader{r: r, hash: crc32.NewIEEE(), expected: m.crc32, size: m.Size, name: m.Name}
+}
+
+// checksumReader verifies the size and the CRC-32 of the data read from r.
+type checksumReader struct {
+	r        io.Reader
+	hash     hash.Hash32
+	expected uint32
+	size     int64
+	read     int64
+	name     string
+}
+
+// Read reads from the underlying reader and returns an error instead of io.EOF if the checksum does not match.
+// It also fails as soon as the data exceeds the size.
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if c.read += int64(n); c.read > c.size {
+		return n, fmt.Errorf("size mismatch of %q: more than %d bytes", c.name, c.size)
+	}
+	if errors.Is(err, io.EOF) {
+		if c.read != c.size {
+			return n, fmt.Errorf("size mismatch of %q: %d bytes instead of %d", c.name, c.read, c.size)
+		}
+		if c.hash.Sum32() != c.expected {
+			return n, fmt.Errorf("checksum mismatch of %q", c.name)
+		}
+	}
+	return n, err
+}
+
+// blockReader reads an io.ReaderAt in blocks of zipBlockSize and keeps them,
+// up to maxZipReadBytes in total.
+type blockReader struct {
+	r      io.ReaderAt
+	size   int64
+	blocks map[int64][]byte
+	read   int64
+}
+
+// newBlockReader creates a blockReader of r of the size.
+func newBlockReader(r io.ReaderAt, size int64) *blockReader {
+	return &blockReader{r: r, size: size, blocks: map[int64][]byte{}}
+}
+
+// ReadAt reads len(p) bytes at off from the blocks, reading missing blocks from the underlying reader.
+func (b *blockReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= b.size {
+			return n, io.EOF
+		}
+		index := pos / zipBlockSize
+		block, found := b.blocks[index]
+		if !found {
+			start := index * zipBlockSize
+			block = make([]byte, min(zipBlockSize, b.size-start))
+			if b.read += int64(len(block)); b.read > maxZipReadBytes {
+				return n, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxZipReadBytes)
+			}
+			if _, err := b.r.ReadAt(block, start); err != nil {
+				return n, err
+			}
+			b.blocks[index] = block
+		}
+		n += copy(p[n:], block[pos-index*zipBlockSize:])
+	}
+	return n, nil
+}
+
+// tarReader returns a tar reader of r, decompressing it if it is gzip-compressed.
+func tarReader(r io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		return tar.NewReader(gr), nil
+	}
+	return tar.NewReader(br), nil
+}
+
+// ReadTarEntries lists the files and folders of a tar archive, which may be gzip-compressed, read as a stream.
+// Links and other special files are skipped.
+// Only the first maxEntries entries in the first maxBytes bytes of r are listed; truncated reports whether
+// there may be more.
+func ReadTarEntries(r io.Reader, maxEntries int, maxBytes int64) (entries []Entry, truncated bool, err error) {
+	limited := &io.LimitedReader{R: r, N: maxBytes}
+	tr, err := tarReader(limited)
+	if err != nil {
+		return nil, false, err
+	}
+	for {
+		header, err := tr.Next()
+		if err != nil && limited.N <= 0 {
+			return entries, true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			continue
+		}
+		name := entryName(header.Name)
+		if header.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") && name != "" {
+			name += "/"
+		}
+		if name == "" {
+			continue
+		}
+		if len(entries) == maxEntries {
+			return entries, true, nil
+		}
+		entries = append(entries, Entry{
+			Name:    name,
+			Size:    header.Size,
+			ModTime: header.ModTime.UTC(),
+			IsDir:   header.Typeflag == tar.TypeDir,
+		})
+	}
+}
+
+// FindTarMember reads a tar archive, which may be gzip-compressed, as a stream up to the file of the name,
+// which must start in the first maxBytes bytes of r.
+// The returned reader reads the content of the file from r.
+func FindTarMember(r io.Reader, name string, maxBytes int64) (*Entry, io.Reader, error) {
+	limited := &io.LimitedReader{R: r, N: maxBytes}
+	tr, err := tarReader(limited)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		header, err := tr.Next()
+		if err != nil && limited.N <= 0 {
+			return nil, nil, fmt.Errorf("%w: %q is not in the first %d bytes", ErrTooLarge, name, maxBytes)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && entryName(header.Name) == name {
+			// The content of the member is not limited
+			limited.N = math.MaxInt64
+			return &Entry{Name: name, Size: header.Size, ModTime: header.ModTime.UTC()}, tr, nil
+		}
+	}
+}
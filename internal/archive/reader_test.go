@@ -0,0 +1,299 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testModTime is the modification time of the files in the test archives.
+var testModTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// countingReaderAt counts the reads of the underlying io.ReaderAt.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int
+}
+
+// ReadAt reads from the underlying reader and counts the read.
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+// testZip builds a ZIP archive with a stored file, deflated files and an explicit folder.
+func testZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name   string
+		method uint16
+		body   string
+	}{
+		{name: "README.txt", method: zip.Store, body: "hello"},
+		{name: "bin/", method: zip.Store},
+		{name: "bin/app", method: zip.Deflate, body: "binary binary binary"},
+		{name: "./lib/a/b.txt", method: zip.Deflate, body: "nested"},
+	}
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method, Modified: testModTime})
+		assert.NoError(t, err)
+		_, err = io.WriteString(w, f.body)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// testTar builds a tar archive with files, a folder and a symbolic link, gzip-compressed if compress is true.
+func testTar(t *testing.T, compress bool) []byte {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(&buf)
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	headers := []struct {
+		header *tar.Header
+		body   string
+	}{
+		{header: &tar.Header{Typeflag: tar.TypeDir, Name: "./bin/"}},
+		{header: &tar.Header{Typeflag: tar.TypeReg, Name: "./bin/app", Size: 3}, body: "app"},
+		{header: &tar.Header{Typeflag: tar.TypeSymlink, Name: "./bin/link", Linkname: "app"}},
+		{header: &tar.Header{Typeflag: tar.TypeReg, Name: "./README.txt", Size: 5}, body: "hello"},
+	}
+	for _, h := range headers {
+		h.header.ModTime = testModTime
+		assert.NoError(t, tw.WriteHeader(h.header))
+		_, err := io.WriteString(tw, h.body)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	if gw != nil {
+		assert.NoError(t, gw.Close())
+	}
+	return buf.Bytes()
+}
+
+// TestIsZip_IsTar tests detecting archives by their keys
+func TestIsZip_IsTar(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		expectedZip bool
+		expectedTar bool
+	}{
+		{name: "ZIP", key: "build/app.ZIP", expectedZip: true},
+		{name: "tar", key: "app.tar", expectedTar: true},
+		{name: "tar.gz", key: "app.tar.gz", expectedTar: true},
+		{name: "tgz", key: "app.tgz", expectedTar: true},
+		{name: "gzip", key: "app.log.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedZip, IsZip(tt.key))
+			assert.Equal(t, tt.expectedTar, IsTar(tt.key))
+		})
+	}
+}
+
+// TestListDir tests listing the entries directly in a folder of an archive
+func TestListDir(t *testing.T) {
+	entries := []Entry{
+		{Name: "README.txt", Size: 5},
+		{Name: "bin/", IsDir: true, ModTime: testModTime},
+		{Name: "bin/app", Size: 20},
+		{Name: "lib/a/b.txt", Size: 6},
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		expected []Entry
+	}{
+		{
+			name: "ルート",
+			dir:  "",
+			expected: []Entry{
+				{Name: "bin/", IsDir: true, ModTime: testModTime},
+				{Name: "lib/", IsDir: true},
+				{Name: "README.txt", Size: 5},
+			},
+		},
+		{
+			name:     "暗黙のフォルダ",
+			dir:      "lib/",
+			expected: []Entry{{Name: "lib/a/", IsDir: true}},
+		},
+		{
+			name:     "ファイルのみ",
+			dir:      "bin/",
+			expected: []Entry{{Name: "bin/app", Size: 20}},
+		},
+		{
+			name:     "存在しないフォルダ",
+			dir:      "none/",
+			expected: []Entry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ListDir(entries, tt.dir))
+		})
+	}
+}
+
+// TestReadZipEntries tests listing a ZIP archive from its central directory
+func TestReadZipEntries(t *testing.T) {
+	data := testZip(t)
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+
+	entries, err := ReadZipEntries(r, int64(len(data)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.reads)
+	assert.Equal(t, []Entry{
+		{Name: "README.txt", Size: 5, ModTime: testModTime},
+		{Name: "bin/", IsDir: true, ModTime: testModTime},
+		{Name: "bin/app", Size: 20, ModTime: testModTime},
+		{Name: "lib/a/b.txt", Size: 6, ModTime: testModTime},
+	}, entries)
+
+	_, err = ReadZipEntries(bytes.NewReader([]byte("not a zip")), 9)
+	assert.ErrorContains(t, err, "failed to read zip directory")
+}
+
+// TestFindZipMember tests reading a file of a ZIP archive from the range of its data
+func TestFindZipMember(t *testing.T) {
+	data := testZip(t)
+
+	tests := []struct {
+		name        string
+		member      string
+		expected    string
+		expectedErr error
+	}{
+		{name: "無圧縮", member: "README.txt", expected: "hello"},
+		{name: "Deflate", member: "bin/app", expected: "binary binary binary"},
+		{name: "パスを正規化", member: "lib/a/b.txt", expected: "nested"},
+		{name: "フォルダ", member: "bin/", expectedErr: ErrNotFound},
+		{name: "存在しない", member: "none", expectedErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, err := FindZipMember(bytes.NewReader(data), int64(len(data)), tt.member)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(tt.expected)), member.Size)
+
+			compressed := io.NewSectionReader(bytes.NewReader(data), member.Offset, member.CompressedSize)
+			content, err := io.ReadAll(member.Reader(compressed))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+		})
+	}
+}
+
+// TestZipMember_Reader_Corrupted tests that data of a ZIP member not matching the central directory is detected
+func TestZipMember_Reader_Corrupted(t *testing.T) {
+	data := testZip(t)
+
+	tests := []struct {
+		name        string
+		size        int64
+		data        string
+		expectedErr string
+	}{
+		{name: "チェックサムが不一致", size: 5, data: "HELLO", expectedErr: "checksum mismatch"},
+		{name: "サイズより長い", size: 3, data: "hello", expectedErr: "more than 3 bytes"},
+		{name: "サイズより短い", size: 8, data: "hello", expectedErr: "5 bytes instead of 8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, err := FindZipMember(bytes.NewReader(data), int64(len(data)), "README.txt")
+			assert.NoError(t, err)
+			member.Size = tt.size
+			member.CompressedSize = int64(len(tt.data))
+
+			_, err = io.ReadAll(member.Reader(strings.NewReader(tt.data)))
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+// TestReadTarEntries tests listing tar archives as a stream
+func TestReadTarEntries(t *testing.T) {
+	expected := []Entry{
+		{Name: "bin/", IsDir: true, ModTime: testModTime},
+		{Name: "bin/app", Size: 3, ModTime: testModTime},
+		{Name: "README.txt", Size: 5, ModTime: testModTime},
+	}
+
+	tests := []struct {
+		name              string
+		data              []byte
+		maxEntries        int
+		maxBytes          int64
+		expected          []Entry
+		expectedTruncated bool
+	}{
+		{name: "tar", data: testTar(t, false), maxEntries: 10, maxBytes: 1 << 20, expected: expected},
+		{name: "tar.gz", data: testTar(t, true), maxEntries: 10, maxBytes: 1 << 20, expected: expected},
+		{name: "最大数で打ち切り", data: testTar(t, true), maxEntries: 2, maxBytes: 1 << 20, expected: expected[:2], expectedTruncated: true},
+		// The headers are 512-byte blocks, so the second header is followed by the content of bin/app
+		{name: "最大バイト数で打ち切り", data: testTar(t, false), maxEntries: 10, maxBytes: 1024, expected: expected[:2], expectedTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, truncated, err := ReadTarEntries(bytes.NewReader(tt.data), tt.maxEntries, tt.maxBytes)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, entries)
+			assert.Equal(t, tt.expectedTruncated, truncated)
+		})
+	}
+}
+
+// TestFindTarMember tests reading a file of a tar archive as a stream
+func TestFindTarMember(t *testing.T) {
+	data := testTar(t, true)
+
+	entry, r, err := FindTarMember(bytes.NewReader(data), "README.txt", 1<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), entry.Size)
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	_, _, err = FindTarMember(bytes.NewReader(data), "bin/link", 1<<20)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// The content of the member is read beyond the limit
+	data = testTar(t, false)
+	_, r, err = FindTarMember(bytes.NewReader(data), "bin/app", 1024)
+	assert.NoError(t, err)
+	content, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", string(content))
+
+	// The member must start within the limit
+	_, _, err = FindTarMember(bytes.NewReader(data), "README.txt", 1024)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
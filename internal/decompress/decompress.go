@@ -0,0 +1,100 @@
+// Package decompress detects compressed objects and decompresses them as a stream.
+package decompress
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is a compression format.
+type Format string
+
+// Supported compression formats.
+const (
+	Gzip  Format = "gzip"
+	Zstd  Format = "zstd"
+	Bzip2 Format = "bzip2"
+)
+
+// maxZstdWindow limits the memory used to decompress a zstd stream.
+const maxZstdWindow = 128 << 20
+
+// extensions are the extensions of compressed objects.
+var extensions = map[string]Format{".gz": Gzip, ".zst": Zstd, ".bz2": Bzip2}
+
+// ByExtension returns the compression format from the extension of the key, or "" if it is not compressed.
+func ByExtension(key string) Format {
+	return extensions[strings.ToLower(path.Ext(key))]
+}
+
+// TrimExtension removes the extension of the compression format from the key, e.g. "app.log.gz" to "app.log".
+func TrimExtension(key string) string {
+	if ByExtension(key) == "" {
+		return key
+	}
+	return strings.TrimSuffix(key, path.Ext(key))
+}
+
+// Detect returns the compression format from the magic number at the beginning of data, or "" if it is unknown.
+func Detect(head []byte) Format {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return Gzip
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return Zstd
+	case len(head) >= 4 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		return Bzip2
+	}
+	return ""
+}
+
+// NewReader returns a reader of the data decompressed from r.
+func NewReader(format Format, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case Gzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		return gr, nil
+	case Zstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(maxZstdWindow))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd stream: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("unsupported compression format %q", format)
+}
+
+// Prefix decompresses the beginning of a compressed object up to maxBytes bytes.
+// If truncated, data is only the beginning of the object, so the stream is expected to end unexpectedly.
+// It reports whether the decompressed data is only the beginning of the content.
+func Prefix(format Format, data []byte, truncated bool, maxBytes int64) ([]byte, bool, error) {
+	r, err := NewReader(format, bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	// A cut stream usually ends with io.ErrUnexpectedEOF, but decoders may report a cut in the middle
+	// of a block as corrupted data, so any error after some output is taken as the end of the beginning
+	if err != nil && !(truncated && (errors.Is(err, io.ErrUnexpectedEOF) || len(decompressed) > 0)) {
+		return nil, false, fmt.Errorf("failed to decompress %s: %w", format, err)
+	}
+	if int64(len(decompressed)) > maxBytes {
+		return decompressed[:maxBytes], true, nil
+	}
+	return decompressed, truncated, nil
+}
@@ -0,0 +1,168 @@
+package decompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// testContent is the content compressed in the tests.
+var testContent = strings.Repeat("2025-01-02T03:04:05Z INFO request served\n", 1000)
+
+// multiBlockContent returns a content that does not compress well, so that it spans several blocks
+// of each format.
+func multiBlockContent() string {
+	rnd := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for b.Len() < 1<<20 {
+		fmt.Fprintf(&b, "2025-01-02T03:04:05Z INFO request %x served\n", rnd.Int63())
+	}
+	return b.String()
+}
+
+// compress compresses the content in the format.
+func compress(t *testing.T, format Format, content string) []byte {
+	var buf bytes.Buffer
+	switch format {
+	case Gzip:
+		w := gzip.NewWriter(&buf)
+		_, err := io.WriteString(w, content)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	case Zstd:
+		w, err := zstd.NewWriter(&buf)
+		assert.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	case Bzip2:
+		// The standard library has no bzip2 compressor
+		cmd := exec.Command("bzip2", "-1", "-c")
+		cmd.Stdin = strings.NewReader(content)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Skip("bzip2 command is not available")
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes()
+}
+
+// TestByExtension tests detecting compression from the key
+func TestByExtension(t *testing.T) {
+	tests := []struct {
+		name            string
+		key             string
+		expected        Format
+		expectedTrimmed string
+	}{
+		{name: "gzip", key: "logs/app.log.GZ", expected: Gzip, expectedTrimmed: "logs/app.log"},
+		{name: "zstd", key: "app.log.zst", expected: Zstd, expectedTrimmed: "app.log"},
+		{name: "bzip2", key: "dump.sql.bz2", expected: Bzip2, expectedTrimmed: "dump.sql"},
+		{name: "非圧縮", key: "app.log", expectedTrimmed: "app.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ByExtension(tt.key))
+			assert.Equal(t, tt.expectedTrimmed, TrimExtension(tt.key))
+		})
+	}
+}
+
+// TestDetect tests detecting compression from the magic number
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     []byte
+		expected Format
+	}{
+		{name: "gzip", head: []byte{0x1f, 0x8b, 0x08}, expected: Gzip},
+		{name: "zstd", head: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04}, expected: Zstd},
+		{name: "bzip2", head: []byte("BZh91AY&SY"), expected: Bzip2},
+		{name: "BZhで始まるテキスト", head: []byte("BZhello")},
+		{name: "テキスト", head: []byte("hello")},
+		{name: "空", head: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Detect(tt.head))
+		})
+	}
+}
+
+// TestPrefix tests decompressing the beginning of compressed objects
+func TestPrefix(t *testing.T) {
+	for _, format := range []Format{Gzip, Zstd, Bzip2} {
+		t.Run(string(format), func(t *testing.T) {
+			data := compress(t, format, testContent)
+
+			decompressed, truncated, err := Prefix(format, data, false, 1<<20)
+			assert.NoError(t, err)
+			assert.Equal(t, testContent, string(decompressed))
+			assert.False(t, truncated)
+
+			decompressed, truncated, err = Prefix(format, data, false, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, testContent[:100], string(decompressed))
+			assert.True(t, truncated)
+
+			decompressed, truncated, err = Prefix(format, data[:len(data)/2], true, 1<<20)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(testContent, string(decompressed)))
+			assert.True(t, truncated)
+		})
+	}
+}
+
+// TestPrefix_Truncated tests decompressing the beginning of objects of several blocks cut at various points
+func TestPrefix_Truncated(t *testing.T) {
+	content := multiBlockContent()
+
+	for _, format := range []Format{Gzip, Zstd, Bzip2} {
+		t.Run(string(format), func(t *testing.T) {
+			data := compress(t, format, content)
+
+			for _, cut := range []int{len(data) / 3, len(data) / 2, len(data) - 10} {
+				decompressed, truncated, err := Prefix(format, data[:cut], true, 1<<20)
+				assert.NoError(t, err)
+				assert.NotEmpty(t, decompressed)
+				assert.True(t, strings.HasPrefix(content, string(decompressed)))
+				assert.True(t, truncated)
+			}
+
+			if format == Gzip {
+				return
+			}
+			// The end of the cut data may look corrupted rather than short.
+			// The output of the last block may then be garbage, but the beginning is kept.
+			corrupted := append(bytes.Clone(data[:len(data)/2]), bytes.Repeat([]byte{0xff}, 64)...)
+			decompressed, truncated, err := Prefix(format, corrupted, true, 1<<20)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(decompressed), content[:1000]))
+			assert.True(t, truncated)
+
+			_, _, err = Prefix(format, corrupted, false, 1<<20)
+			assert.ErrorContains(t, err, "failed to decompress "+string(format))
+		})
+	}
+}
+
+// TestPrefix_Error tests that invalid compressed data is reported
+func TestPrefix_Error(t *testing.T) {
+	_, _, err := Prefix(Gzip, []byte("not gzip"), false, 1<<20)
+	assert.ErrorContains(t, err, "failed to read gzip header")
+
+	data := compress(t, Gzip, testContent)
+	_, _, err = Prefix(Gzip, data[:len(data)/2], false, 1<<20)
+	assert.ErrorContains(t, err, "failed to decompress gzip")
+}
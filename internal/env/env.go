@@ -2,6 +2,8 @@ package env
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,28 +15,172 @@ const (
 	EnvKeyPort        = "PB_PORT"
 	EnvKeyIPAddress   = "PB_IP_ADDRESS"
 	EnvKeySiteName    = "PB_SITE_NAME"
+	EnvKeyPageSize    = "PB_PAGE_SIZE"
+
+	EnvKeyCacheDuration        = "PB_CACHE_DURATION"
+	EnvKeyCacheStaleDuration   = "PB_CACHE_STALE_DURATION"
+	EnvKeyBucketsCacheDuration = "PB_BUCKETS_CACHE_DURATION"
+	EnvKeyCacheBackend         = "PB_CACHE_BACKEND"
+	EnvKeyCacheMaxEntries      = "PB_CACHE_MAX_ENTRIES"
+	EnvKeyCacheMaxBytes        = "PB_CACHE_MAX_BYTES"
+	EnvKeyCacheDiskPath        = "PB_CACHE_DISK_PATH"
+
+	EnvKeyCacheControl       = "PB_CACHE_CONTROL"
+	EnvKeyBucketCacheControl = "PB_BUCKET_CACHE_CONTROL"
+
+	EnvKeyPresignBuckets  = "PB_PRESIGN_BUCKETS"
+	EnvKeyPresignExpiry   = "PB_PRESIGN_EXPIRY"
+	EnvKeyPresignEndpoint = "PB_PRESIGN_ENDPOINT"
+
+	EnvKeyArchiveMaxObjects = "PB_ARCHIVE_MAX_OBJECTS"
+	EnvKeyArchiveMaxBytes   = "PB_ARCHIVE_MAX_BYTES"
+
+	EnvKeyPreviewMaxBytes        = "PB_PREVIEW_MAX_BYTES"
+	EnvKeyThumbnailMaxBytes      = "PB_THUMBNAIL_MAX_BYTES"
+	EnvKeyThumbnailCacheMaxBytes = "PB_THUMBNAIL_CACHE_MAX_BYTES"
+	EnvKeyTailPollInterval       = "PB_TAIL_POLL_INTERVAL"
+	EnvKeyTailMaxFollowers       = "PB_TAIL_MAX_FOLLOWERS"
+
+	EnvKeyAdminToken   = "PB_ADMIN_TOKEN"
+	EnvKeyWebhookToken = "PB_WEBHOOK_TOKEN"
+)
+
+// DefaultPageSize is the default number of keys per page, which is also the maximum of ListObjectsV2.
+const DefaultPageSize = 1000
+
+// Default bounds of the LRU listObjects cache.
+const (
+	DefaultCacheMaxEntries = 10000
+	DefaultCacheMaxBytes   = 256 << 20
+)
+
+// DefaultCacheStaleDuration is the default duration an expired listObjects cache entry is served while it is refreshed.
+const DefaultCacheStaleDuration = 24 * time.Hour
+
+// Default limits of folder archive downloads.
+const (
+	DefaultArchiveMaxObjects = 10000
+	DefaultArchiveMaxBytes   = 5 << 30
 )
 
+// DefaultPreviewMaxBytes is the default number of bytes read from the beginning of an object for a preview.
+const DefaultPreviewMaxBytes = 1 << 20
+
+// Default limits of thumbnails.
+const (
+	DefaultThumbnailMaxBytes      = 32 << 20
+	DefaultThumbnailCacheMaxBytes = 64 << 20
+)
+
+// DefaultTailPollInterval is the default interval of checking a followed object for growth.
+const DefaultTailPollInterval = 5 * time.Second
+
+// DefaultTailMaxFollowers is the default maximum number of objects followed at the same time.
+const DefaultTailMaxFollowers = 100
+
+// DefaultCacheDiskPath is the default file of the disk listObjects cache.
+const DefaultCacheDiskPath = "polybuckets-cache.db"
+
 // PBConfigType holds the configuration values loaded from environment variables.
 type PBConfigType struct {
-	AWSRegion     string
-	AWSProfile    string
-	AWSEndpoint   string
-	Port          string
-	IPAddress     string
-	CacheDuration time.Duration
-	SiteName      string
+	AWSRegion            string
+	AWSProfile           string
+	AWSEndpoint          string
+	Port                 string
+	IPAddress            string
+	CacheDuration        time.Duration
+	CacheStaleDuration   time.Duration
+	BucketsCacheDuration time.Duration
+	SiteName             string
+	PageSize             int32
+
+	CacheBackend    string
+	CacheMaxEntries int
+	CacheMaxBytes   int64
+	CacheDiskPath   string
+
+	AdminToken   string
+	WebhookToken string
+
+	CacheControl       string
+	BucketCacheControl map[string]string
+
+	PresignBuckets  []string
+	PresignExpiry   time.Duration
+	PresignEndpoint string
+
+	ArchiveMaxObjects int
+	ArchiveMaxBytes   int64
+
+	PreviewMaxBytes        int64
+	ThumbnailMaxBytes      int64
+	ThumbnailCacheMaxBytes int64
+	TailPollInterval       time.Duration
+	TailMaxFollowers       int
+}
+
+// CacheControlFor returns the Cache-Control header value of downloads from the specified bucket.
+func (c *PBConfigType) CacheControlFor(bucket string) string {
+	if cacheControl, ok := c.BucketCacheControl[bucket]; ok {
+		return cacheControl
+	}
+	return c.CacheControl
+}
+
+// PresignEnabledFor reports whether downloads from the specified bucket are redirected to presigned URLs.
+func (c *PBConfigType) PresignEnabledFor(bucket string) bool {
+	for _, b := range c.PresignBuckets {
+		if b == "*" || b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList parses a comma-separated list, ignoring empty elements.
+func parseList(s string) []string {
+	var result []string
+	for _, element := range strings.Split(s, ",") {
+		element = strings.TrimSpace(element)
+		if element != "" {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// parseBucketMap parses a per-bucket setting in the form of "bucket1=value1;bucket2=value2".
+// Entries without "=" are ignored.
+func parseBucketMap(s string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(s, ";") {
+		bucket, value, found := strings.Cut(entry, "=")
+		bucket = strings.TrimSpace(bucket)
+		if !found || bucket == "" {
+			continue
+		}
+		result[bucket] = strings.TrimSpace(value)
+	}
+	return result
 }
 
 // LoadPBConfig loads the configuration from environment variables.
 func loadPBConfig() *PBConfigType {
 	pbConfig := &PBConfigType{
-		AWSRegion:   os.Getenv("AWS_REGION"),
-		AWSProfile:  os.Getenv("AWS_PROFILE"),
-		AWSEndpoint: os.Getenv("AWS_ENDPOINT"),
-		Port:        os.Getenv("PB_PORT"),
-		IPAddress:   os.Getenv("PB_IP_ADDRESS"),
-		SiteName:    os.Getenv("PB_SITE_NAME"),
+		AWSRegion:    os.Getenv("AWS_REGION"),
+		AWSProfile:   os.Getenv("AWS_PROFILE"),
+		AWSEndpoint:  os.Getenv("AWS_ENDPOINT"),
+		Port:         os.Getenv("PB_PORT"),
+		IPAddress:    os.Getenv("PB_IP_ADDRESS"),
+		SiteName:     os.Getenv("PB_SITE_NAME"),
+		AdminToken:   os.Getenv(EnvKeyAdminToken),
+		WebhookToken: os.Getenv(EnvKeyWebhookToken),
+
+		CacheControl:       os.Getenv(EnvKeyCacheControl),
+		BucketCacheControl: parseBucketMap(os.Getenv(EnvKeyBucketCacheControl)),
+
+		PresignBuckets:  parseList(os.Getenv(EnvKeyPresignBuckets)),
+		PresignEndpoint: os.Getenv(EnvKeyPresignEndpoint),
 	}
 
 	// デフォルトのサイト名を設定
@@ -42,13 +188,115 @@ func loadPBConfig() *PBConfigType {
 		pbConfig.SiteName = "polybuckets"
 	}
 
-	if os.Getenv("PB_CACHE_DURATION") != "" {
-		duration, err := time.ParseDuration(os.Getenv("CACHE_DURATION"))
+	pbConfig.CacheDuration = 60 * time.Minute
+	if os.Getenv(EnvKeyCacheDuration) != "" {
+		duration, err := time.ParseDuration(os.Getenv(EnvKeyCacheDuration))
 		if err == nil {
 			pbConfig.CacheDuration = duration
 		}
-	} else {
-		pbConfig.CacheDuration = 60 * time.Minute
+	}
+	pbConfig.BucketsCacheDuration = 60 * time.Minute
+	if os.Getenv(EnvKeyBucketsCacheDuration) != "" {
+		duration, err := time.ParseDuration(os.Getenv(EnvKeyBucketsCacheDuration))
+		if err == nil {
+			pbConfig.BucketsCacheDuration = duration
+		}
+	}
+	pbConfig.CacheStaleDuration = DefaultCacheStaleDuration
+	if os.Getenv(EnvKeyCacheStaleDuration) != "" {
+		duration, err := time.ParseDuration(os.Getenv(EnvKeyCacheStaleDuration))
+		if err == nil {
+			pbConfig.CacheStaleDuration = duration
+		}
+	}
+
+	pbConfig.CacheBackend = os.Getenv(EnvKeyCacheBackend)
+	pbConfig.CacheMaxEntries = DefaultCacheMaxEntries
+	if os.Getenv(EnvKeyCacheMaxEntries) != "" {
+		maxEntries, err := strconv.Atoi(os.Getenv(EnvKeyCacheMaxEntries))
+		if err == nil {
+			pbConfig.CacheMaxEntries = maxEntries
+		}
+	}
+	pbConfig.CacheMaxBytes = DefaultCacheMaxBytes
+	if os.Getenv(EnvKeyCacheMaxBytes) != "" {
+		maxBytes, err := strconv.ParseInt(os.Getenv(EnvKeyCacheMaxBytes), 10, 64)
+		if err == nil {
+			pbConfig.CacheMaxBytes = maxBytes
+		}
+	}
+	pbConfig.CacheDiskPath = os.Getenv(EnvKeyCacheDiskPath)
+	if pbConfig.CacheDiskPath == "" {
+		pbConfig.CacheDiskPath = DefaultCacheDiskPath
+	}
+
+	pbConfig.PageSize = DefaultPageSize
+	if os.Getenv(EnvKeyPageSize) != "" {
+		pageSize, err := strconv.ParseInt(os.Getenv(EnvKeyPageSize), 10, 32)
+		if err == nil && pageSize > 0 && pageSize <= DefaultPageSize {
+			pbConfig.PageSize = int32(pageSize)
+		}
+	}
+
+	pbConfig.PresignExpiry = 5 * time.Minute
+	if os.Getenv(EnvKeyPresignExpiry) != "" {
+		duration, err := time.ParseDuration(os.Getenv(EnvKeyPresignExpiry))
+		if err == nil {
+			pbConfig.PresignExpiry = duration
+		}
+	}
+
+	pbConfig.ArchiveMaxObjects = DefaultArchiveMaxObjects
+	if os.Getenv(EnvKeyArchiveMaxObjects) != "" {
+		maxObjects, err := strconv.Atoi(os.Getenv(EnvKeyArchiveMaxObjects))
+		if err == nil {
+			pbConfig.ArchiveMaxObjects = maxObjects
+		}
+	}
+	pbConfig.ArchiveMaxBytes = DefaultArchiveMaxBytes
+	if os.Getenv(EnvKeyArchiveMaxBytes) != "" {
+		maxBytes, err := strconv.ParseInt(os.Getenv(EnvKeyArchiveMaxBytes), 10, 64)
+		if err == nil {
+			pbConfig.ArchiveMaxBytes = maxBytes
+		}
+	}
+
+	pbConfig.PreviewMaxBytes = DefaultPreviewMaxBytes
+	if os.Getenv(EnvKeyPreviewMaxBytes) != "" {
+		maxBytes, err := strconv.ParseInt(os.Getenv(EnvKeyPreviewMaxBytes), 10, 64)
+		if err == nil && maxBytes > 0 {
+			pbConfig.PreviewMaxBytes = maxBytes
+		}
+	}
+
+	pbConfig.ThumbnailMaxBytes = DefaultThumbnailMaxBytes
+	if os.Getenv(EnvKeyThumbnailMaxBytes) != "" {
+		maxBytes, err := strconv.ParseInt(os.Getenv(EnvKeyThumbnailMaxBytes), 10, 64)
+		if err == nil {
+			pbConfig.ThumbnailMaxBytes = maxBytes
+		}
+	}
+	pbConfig.ThumbnailCacheMaxBytes = DefaultThumbnailCacheMaxBytes
+	if os.Getenv(EnvKeyThumbnailCacheMaxBytes) != "" {
+		maxBytes, err := strconv.ParseInt(os.Getenv(EnvKeyThumbnailCacheMaxBytes), 10, 64)
+		if err == nil {
+			pbConfig.ThumbnailCacheMaxBytes = maxBytes
+		}
+	}
+
+	pbConfig.TailPollInterval = DefaultTailPollInterval
+	if os.Getenv(EnvKeyTailPollInterval) != "" {
+		duration, err := time.ParseDuration(os.Getenv(EnvKeyTailPollInterval))
+		if err == nil && duration > 0 {
+			pbConfig.TailPollInterval = duration
+		}
+	}
+	pbConfig.TailMaxFollowers = DefaultTailMaxFollowers
+	if os.Getenv(EnvKeyTailMaxFollowers) != "" {
+		maxFollowers, err := strconv.Atoi(os.Getenv(EnvKeyTailMaxFollowers))
+		if err == nil {
+			pbConfig.TailMaxFollowers = maxFollowers
+		}
 	}
 
 	// Set UTC as the default timezone
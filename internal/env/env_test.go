@@ -0,0 +1,77 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseBucketMap tests the parseBucketMap function with various inputs.
+func TestParseBucketMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:     "空文字列",
+			input:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:  "複数のバケット",
+			input: "bucket1=public, max-age=3600; bucket2=no-cache",
+			expected: map[string]string{
+				"bucket1": "public, max-age=3600",
+				"bucket2": "no-cache",
+			},
+		},
+		{
+			name:  "不正なエントリを無視",
+			input: "bucket1;=value;bucket2=",
+			expected: map[string]string{
+				"bucket2": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseBucketMap(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestPBConfigType_CacheControlFor tests that per-bucket Cache-Control overrides the default.
+func TestPBConfigType_CacheControlFor(t *testing.T) {
+	pbConfig := &PBConfigType{
+		CacheControl:       "private, max-age=60",
+		BucketCacheControl: map[string]string{"bucket1": "public, max-age=3600"},
+	}
+
+	assert.Equal(t, "public, max-age=3600", pbConfig.CacheControlFor("bucket1"))
+	assert.Equal(t, "private, max-age=60", pbConfig.CacheControlFor("bucket2"))
+}
+
+// TestPBConfigType_PresignEnabledFor tests the per-bucket opt-in of presigned downloads.
+func TestPBConfigType_PresignEnabledFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		buckets  string
+		bucket   string
+		expected bool
+	}{
+		{name: "未設定", buckets: "", bucket: "bucket1", expected: false},
+		{name: "対象のバケット", buckets: "bucket1, bucket2", bucket: "bucket2", expected: true},
+		{name: "対象外のバケット", buckets: "bucket1,bucket2", bucket: "bucket3", expected: false},
+		{name: "すべてのバケット", buckets: "*", bucket: "bucket3", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pbConfig := &PBConfigType{PresignBuckets: parseList(tt.buckets)}
+			assert.Equal(t, tt.expected, pbConfig.PresignEnabledFor(tt.bucket))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentDisposition builds a Content-Disposition header value as described in RFC 6266.
+// The filename is given both as an ASCII fallback and as a UTF-8 encoded `filename*` parameter.
+func ContentDisposition(dispositionType, filename string) string {
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`,
+		dispositionType, asciiFallback(filename), encodeRFC5987(filename))
+}
+
+// asciiFallback replaces characters that cannot appear in a quoted ASCII filename with underscores.
+func asciiFallback(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// encodeRFC5987 percent-encodes all bytes of s except attr-char defined in RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// isAttrChar reports whether c is an attr-char of RFC 5987.
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
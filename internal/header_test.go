@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestContentDisposition tests the ContentDisposition function with various filenames.
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name            string
+		dispositionType string
+		filename        string
+		expected        string
+	}{
+		{
+			name:            "ASCIIのファイル名",
+			dispositionType: "attachment",
+			filename:        "test_0.txt",
+			expected:        `attachment; filename="test_0.txt"; filename*=UTF-8''test_0.txt`,
+		},
+		{
+			name:            "空白と記号を含むファイル名",
+			dispositionType: "attachment",
+			filename:        `my "file" (1).txt`,
+			expected:        `attachment; filename="my _file_ (1).txt"; filename*=UTF-8''my%20%22file%22%20%281%29.txt`,
+		},
+		{
+			name:            "非ASCIIのファイル名",
+			dispositionType: "inline",
+			filename:        "日本語.txt",
+			expected:        `inline; filename="___.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC%E8%AA%9E.txt`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ContentDisposition(tt.dispositionType, tt.filename)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package lru implements an in-memory cache that evicts the least recently used entries first.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is an in-memory cache bounded by entry count and approximate bytes.
+// The least recently used entries are evicted first when a bound is exceeded. It is safe for concurrent use.
+type Cache[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	sizeOf     func(V) int64
+	bytes      int64
+	order      *list.List
+	items      map[string]*list.Element
+}
+
+// item is the value stored in each element of Cache.order.
+type item[V any] struct {
+	key   string
+	value V
+	size  int64
+}
+
+// New creates an empty cache.
+// maxEntries and maxBytes bound the cache; a value of 0 or less disables that bound.
+// sizeOf returns the approximate number of bytes of a value; it may be nil if maxBytes is not used.
+func New[V any](maxEntries int, maxBytes int64, sizeOf func(V) int64) *Cache[V] {
+	return &Cache[V]{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		sizeOf:     sizeOf,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value for the specified key and marks it as recently used.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, found := c.items[key]
+	if !found {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*item[V]).value, true
+}
+
+// Set stores the value for the specified key and evicts entries until the bounds are satisfied.
+// A value larger than maxBytes is not kept.
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	if c.sizeOf != nil {
+		size = c.sizeOf(value)
+	}
+	if elem, found := c.items[key]; found {
+		it := elem.Value.(*item[V])
+		c.bytes += size - it.size
+		it.value = value
+		it.size = size
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(&item[V]{key: key, value: value, size: size})
+		c.bytes += size
+	}
+
+	for c.order.Len() > 0 && c.exceeded() {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// exceeded reports whether the cache is over one of its bounds. The caller must hold c.mu.
+func (c *Cache[V]) exceeded() bool {
+	return (c.maxEntries > 0 && c.order.Len() > c.maxEntries) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+// removeElement removes the element from the cache. The caller must hold c.mu.
+func (c *Cache[V]) removeElement(elem *list.Element) {
+	it := c.order.Remove(elem).(*item[V])
+	delete(c.items, it.key)
+	c.bytes -= it.size
+}
+
+// DeleteFunc removes all entries for which fn returns true. fn must not call other methods of the cache.
+func (c *Cache[V]) DeleteFunc(fn func(key string, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.items {
+		if fn(key, elem.Value.(*item[V]).value) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Bytes returns the approximate number of bytes of the entries in the cache.
+func (c *Cache[V]) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// Range calls fn for each entry from the most recently used until fn returns false.
+// It does not change the recency of the entries. fn must not call other methods of the cache.
+func (c *Cache[V]) Range(fn func(key string, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		it := elem.Value.(*item[V])
+		if !fn(it.key, it.value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package lru
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCache_MaxEntries tests that the least recently used entry is evicted when the entry count is exceeded
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New[int](2, 0, nil)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	_, found := cache.Get("a")
+	assert.True(t, found)
+
+	cache.Set("c", 3)
+	assert.Equal(t, 2, cache.Len())
+	_, found = cache.Get("b")
+	assert.False(t, found)
+	value, found := cache.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+	_, found = cache.Get("c")
+	assert.True(t, found)
+}
+
+// TestCache_MaxBytes tests that entries are evicted when the approximate size is exceeded
+func TestCache_MaxBytes(t *testing.T) {
+	cache := New(0, 3, func(v string) int64 { return int64(len(v)) })
+	cache.Set("a", "x")
+	cache.Set("b", "x")
+	cache.Set("c", "x")
+	assert.Equal(t, 3, cache.Len())
+	assert.Equal(t, int64(3), cache.Bytes())
+
+	// A larger value evicts the least recently used entries until it fits
+	cache.Set("d", "xx")
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	_, found = cache.Get("b")
+	assert.False(t, found)
+	_, found = cache.Get("d")
+	assert.True(t, found)
+	assert.Equal(t, int64(3), cache.Bytes())
+
+	// Replacing a value updates the bytes
+	cache.Set("d", "x")
+	assert.Equal(t, int64(2), cache.Bytes())
+
+	// A value larger than the whole cache is not kept
+	cache.Set("e", "xxxx")
+	_, found = cache.Get("e")
+	assert.False(t, found)
+	assert.LessOrEqual(t, cache.Bytes(), int64(3))
+}
+
+// TestCache_DeleteFunc tests that matching entries are removed and their bytes released
+func TestCache_DeleteFunc(t *testing.T) {
+	cache := New(0, 0, func(v string) int64 { return int64(len(v)) })
+	cache.Set("dir/a", "aa")
+	cache.Set("dir/b", "bbb")
+	cache.Set("other", "c")
+
+	cache.DeleteFunc(func(key string, value string) bool {
+		return strings.HasPrefix(key, "dir/")
+	})
+	assert.Equal(t, 1, cache.Len())
+	assert.Equal(t, int64(1), cache.Bytes())
+
+	var keys []string
+	cache.Range(func(key string, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []string{"other"}, keys)
+}
@@ -0,0 +1,226 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// AvroFile is the header and the first records of an Avro object container file.
+type AvroFile struct {
+	// Schema is the writer schema as indented JSON.
+	Schema string
+	Codec  string
+	Table  *Table
+}
+
+// ReadAvro decodes the header and up to maxRows records from the beginning of an Avro object container file.
+// truncated reports whether data is only the beginning of the file; records in a block cut off at the end are not shown.
+func ReadAvro(data []byte, truncated bool, maxRows int) (*AvroFile, error) {
+	// The sizes in the file are checked before decoding since the decoder allocates them as they are
+	data, err := completeAvroBlocks(data, truncated)
+	if err != nil {
+		return nil, err
+	}
+	config := avro.Config{MaxByteSliceSize: len(data), MaxSliceAllocSize: len(data)}.Freeze()
+	decoder, err := ocf.NewDecoder(bytes.NewReader(data), ocf.WithDecoderConfig(config), ocf.WithDecoderSchemaCache(&avro.SchemaCache{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Avro header: %w", err)
+	}
+	if err := checkAvroFixedSizes(decoder.Schema(), len(data), map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	metadata := decoder.Metadata()
+	file := &AvroFile{Codec: string(metadata["avro.codec"]), Table: &Table{}}
+	if file.Codec == "" {
+		file.Codec = "null"
+	}
+	var schema bytes.Buffer
+	if err := json.Indent(&schema, metadata["avro.schema"], "", "  "); err != nil {
+		schema.Reset()
+		schema.Write(metadata["avro.schema"])
+	}
+	file.Schema = schema.String()
+
+	// Records are shown with a column for each field, other values in a single column
+	var fields []string
+	if record, ok := decoder.Schema().(*avro.RecordSchema); ok {
+		for _, field := range record.Fields() {
+			fields = append(fields, field.Name())
+		}
+		file.Table.Header = fields
+	} else {
+		file.Table.Header = []string{"value"}
+	}
+
+	for decoder.HasNext() {
+		if len(file.Table.Rows) == maxRows {
+			file.Table.Truncated = true
+			return file, nil
+		}
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode Avro record: %w", err)
+		}
+		record, ok := value.(map[string]interface{})
+		if fields == nil || !ok {
+			file.Table.Rows = append(file.Table.Rows, []string{formatAvroValue(value)})
+			continue
+		}
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			row[i] = formatAvroValue(record[field])
+		}
+		file.Table.Rows = append(file.Table.Rows, row)
+	}
+	if err := decoder.Error(); err != nil && !truncated {
+		return nil, fmt.Errorf("failed to decode Avro block: %w", err)
+	}
+	file.Table.Truncated = truncated
+	return file, nil
+}
+
+// completeAvroBlocks checks the header and the block sizes of an Avro object container file against the length of data.
+// If data is only the beginning of the file, it returns data without the block cut off at the end.
+func completeAvroBlocks(data []byte, truncated bool) ([]byte, error) {
+	pos := 0
+	readLong := func() (int64, bool) {
+		v, n := binary.Varint(data[pos:])
+		if n <= 0 {
+			return 0, false
+		}
+		pos += n
+		return v, true
+	}
+	skipBytes := func() bool {
+		size, ok := readLong()
+		if !ok || size < 0 || size > int64(len(data)-pos) {
+			return false
+		}
+		pos += int(size)
+		return true
+	}
+
+	// The header is the magic, the metadata map and the sync marker
+	errHeader := errors.New("failed to decode Avro header: invalid header")
+	if len(data) < 4 || !bytes.Equal(data[:4], []byte("Obj\x01")) {
+		return nil, errHeader
+	}
+	pos = 4
+	for {
+		count, ok := readLong()
+		if !ok {
+			return nil, errHeader
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			// A negative count is followed by the size of the block
+			count = -count
+			if _, ok := readLong(); !ok {
+				return nil, errHeader
+			}
+		}
+		for ; count > 0; count-- {
+			if !skipBytes() || !skipBytes() {
+				return nil, errHeader
+			}
+		}
+	}
+	if len(data)-pos < 16 {
+		return nil, errHeader
+	}
+	pos += 16
+
+	// Each block is the number of records, the size, the records and the sync marker
+	for pos < len(data) {
+		start := pos
+		count, ok1 := readLong()
+		size, ok2 := readLong()
+		if ok1 && ok2 && (count < 0 || size < 0) {
+			return nil, fmt.Errorf("failed to decode Avro block: invalid block of %d records in %d bytes", count, size)
+		}
+		if !ok1 || !ok2 || size > int64(len(data)-pos-16) {
+			if truncated {
+				return data[:start], nil
+			}
+			return nil, errors.New("failed to decode Avro block: the block exceeds the file")
+		}
+		pos += int(size) + 16
+	}
+	return data, nil
+}
+
+// checkAvroFixedSizes checks that the fixed types in the schema are not larger than the file of size bytes,
+// since the decoder allocates them as they are.
+func checkAvroFixedSizes(schema avro.Schema, size int, seen map[string]bool) error {
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return checkAvroFixedSizes(s.Schema(), size, seen)
+	case *avro.FixedSchema:
+		if s.Size() < 0 || s.Size() > size {
+			return fmt.Errorf("failed to parse Avro schema: invalid size %d of %s", s.Size(), s.FullName())
+		}
+	case *avro.RecordSchema:
+		// Records can refer to themselves
+		if seen[s.FullName()] {
+			return nil
+		}
+		seen[s.FullName()] = true
+		for _, field := range s.Fields() {
+			if err := checkAvroFixedSizes(field.Type(), size, seen); err != nil {
+				return err
+			}
+		}
+	case *avro.ArraySchema:
+		return checkAvroFixedSizes(s.Items(), size, seen)
+	case *avro.MapSchema:
+		return checkAvroFixedSizes(s.Values(), size, seen)
+	case *avro.UnionSchema:
+		for _, t := range s.Types() {
+			if err := checkAvroFixedSizes(t, size, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatAvroValue returns a decoded Avro value as a string. Complex values are shown as JSON.
+func formatAvroValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return "0x" + hex.EncodeToString(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case []interface{}:
+		// Empty arrays are decoded as nil
+		if v == nil {
+			return "[]"
+		}
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
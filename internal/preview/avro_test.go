@@ -0,0 +1,150 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAvroSchema is the schema of the Avro files in the tests.
+const testAvroSchema = `{"type":"record","name":"user","fields":[` +
+	`{"name":"name","type":"string"},{"name":"age","type":["null","int"]},{"name":"tags","type":{"type":"array","items":"string"}}]}`
+
+// testAvroUser is a record of testAvroSchema.
+type testAvroUser struct {
+	Name string   `avro:"name"`
+	Age  *int     `avro:"age"`
+	Tags []string `avro:"tags"`
+}
+
+// testAvroFile builds an Avro object container file of the records with blocks of blockLength records.
+func testAvroFile(t *testing.T, blockLength int, users ...testAvroUser) []byte {
+	var buf bytes.Buffer
+	encoder, err := ocf.NewEncoder(testAvroSchema, &buf, ocf.WithCodec(ocf.Deflate), ocf.WithBlockLength(blockLength))
+	assert.NoError(t, err)
+	for _, user := range users {
+		assert.NoError(t, encoder.Encode(user))
+	}
+	assert.NoError(t, encoder.Close())
+	return buf.Bytes()
+}
+
+// TestReadAvro tests reading the header and the first records of an Avro file
+func TestReadAvro(t *testing.T) {
+	age := 30
+	data := testAvroFile(t, 2,
+		testAvroUser{Name: "alice", Age: &age, Tags: []string{"a", "b"}},
+		testAvroUser{Name: "bob"},
+		testAvroUser{Name: "carol"},
+	)
+
+	tests := []struct {
+		name              string
+		data              []byte
+		truncated         bool
+		maxRows           int
+		expectedRows      [][]string
+		expectedTruncated bool
+	}{
+		{
+			name:         "全レコード",
+			data:         data,
+			maxRows:      10,
+			expectedRows: [][]string{{"alice", "30", `["a","b"]`}, {"bob", "", "[]"}, {"carol", "", "[]"}},
+		},
+		{
+			name:              "最大行数で打ち切り",
+			data:              data,
+			maxRows:           1,
+			expectedRows:      [][]string{{"alice", "30", `["a","b"]`}},
+			expectedTruncated: true,
+		},
+		{
+			name:              "途中で切れたブロックは除外",
+			data:              data[:len(data)-20],
+			truncated:         true,
+			maxRows:           10,
+			expectedRows:      [][]string{{"alice", "30", `["a","b"]`}, {"bob", "", "[]"}},
+			expectedTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ReadAvro(tt.data, tt.truncated, tt.maxRows)
+			assert.NoError(t, err)
+			assert.Equal(t, "deflate", file.Codec)
+			assert.Contains(t, file.Schema, `"name": "user"`)
+			assert.Equal(t, []string{"name", "age", "tags"}, file.Table.Header)
+			assert.Equal(t, tt.expectedRows, file.Table.Rows)
+			assert.Equal(t, tt.expectedTruncated, file.Table.Truncated)
+		})
+	}
+}
+
+// TestReadAvro_Error tests that invalid files are reported
+func TestReadAvro_Error(t *testing.T) {
+	_, err := ReadAvro([]byte("not avro"), false, 10)
+	assert.ErrorContains(t, err, "failed to decode Avro header")
+}
+
+// TestReadAvro_Malformed tests that sizes in a malformed file are rejected before they are allocated
+func TestReadAvro_Malformed(t *testing.T) {
+	header := testAvroFile(t, 2)
+	var fixedHeader bytes.Buffer
+	encoder, err := ocf.NewEncoder(`{"type":"fixed","name":"f","size":1099511627776}`, &fixedHeader)
+	assert.NoError(t, err)
+	assert.NoError(t, encoder.Close())
+	block := func(count, size int64) []byte {
+		return binary.AppendVarint(binary.AppendVarint(bytes.Clone(header), count), size)
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		truncated     bool
+		expectedError string
+	}{
+		{
+			name:          "巨大なメタデータ",
+			data:          binary.AppendVarint([]byte("Obj\x01\x02\x02k"), 1<<40),
+			expectedError: "failed to decode Avro header",
+		},
+		{
+			name:          "負のブロックサイズ",
+			data:          block(1, -1),
+			expectedError: "invalid block of 1 records in -1 bytes",
+		},
+		{
+			name:          "負のレコード数",
+			data:          block(-1, 0),
+			truncated:     true,
+			expectedError: "invalid block of -1 records in 0 bytes",
+		},
+		{
+			name:          "巨大なブロックサイズ",
+			data:          block(1, 1<<40),
+			expectedError: "the block exceeds the file",
+		},
+		{
+			name:          "巨大なfixed",
+			data:          fixedHeader.Bytes(),
+			expectedError: "invalid size 1099511627776 of f",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadAvro(tt.data, tt.truncated, 10)
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+
+	// The block cut off at the end of the beginning of a file is not shown
+	file, err := ReadAvro(block(1, 1<<40), true, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, file.Table.Rows)
+}
@@ -0,0 +1,40 @@
+package preview
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// highlightStyle is the chroma style of highlighted text.
+const highlightStyle = "github"
+
+// Highlight returns the source as HTML with line numbers, highlighted for the language
+// guessed from the filename or, failing that, from the source itself.
+func Highlight(filename, source string) (template.HTML, error) {
+	lexer := lexers.Match(filename)
+	if lexer == nil {
+		lexer = lexers.Analyse(source)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to tokenise %q: %w", filename, err)
+	}
+
+	var buf strings.Builder
+	formatter := html.New(html.WithLineNumbers(true), html.TabWidth(4))
+	if err := formatter.Format(&buf, styles.Get(highlightStyle), iterator); err != nil {
+		return "", fmt.Errorf("failed to highlight %q: %w", filename, err)
+	}
+	// chroma escapes the source, so the result is safe to embed
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,29 @@
+package preview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHighlight tests that the source is highlighted and escaped
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		source   string
+		contains string
+	}{
+		{name: "ファイル名から言語を判定", filename: "main.go", source: "package main\n", contains: ">package</span>"},
+		{name: "HTMLはエスケープされる", filename: "note.txt", source: "<script>alert(1)</script>", contains: "&lt;script&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Highlight(tt.filename, tt.source)
+			assert.NoError(t, err)
+			assert.Contains(t, string(result), tt.contains)
+			assert.NotContains(t, string(result), "<script>")
+		})
+	}
+}
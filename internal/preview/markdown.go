@@ -0,0 +1,81 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// LinkResolver converts a relative link destination in a Markdown object to a URL.
+// image is true for the source of an image.
+type LinkResolver func(dest string, image bool) string
+
+// markdownPolicy sanitizes the rendered HTML since bucket content is untrusted.
+var markdownPolicy = bluemonday.UGCPolicy()
+
+// RenderMarkdown renders GitHub Flavored Markdown to sanitized HTML.
+// Raw HTML in the source is allowed as far as it survives the sanitization.
+// Relative links and images are converted with resolve if it is not nil.
+func RenderMarkdown(source []byte, resolve LinkResolver) (template.HTML, error) {
+	options := []parser.Option{parser.WithAutoHeadingID()}
+	if resolve != nil {
+		options = append(options, parser.WithASTTransformers(util.Prioritized(&linkTransformer{resolve: resolve}, 100)))
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(options...),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// linkTransformer rewrites the relative destinations of links and images with a LinkResolver.
+type linkTransformer struct {
+	resolve LinkResolver
+}
+
+// Transform implements parser.ASTTransformer.
+func (t *linkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			if isRelativeLink(string(node.Destination)) {
+				node.Destination = []byte(t.resolve(string(node.Destination), false))
+			}
+		case *ast.Image:
+			if isRelativeLink(string(node.Destination)) {
+				node.Destination = []byte(t.resolve(string(node.Destination), true))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isRelativeLink reports whether the destination is relative to the Markdown object,
+// i.e. it has neither a scheme, a host nor an absolute path, and is not a fragment of the page.
+func isRelativeLink(dest string) bool {
+	if dest == "" || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") {
+		return false
+	}
+	u, err := url.Parse(dest)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
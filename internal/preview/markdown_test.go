@@ -0,0 +1,55 @@
+package preview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRenderMarkdown tests rendering and sanitizing Markdown
+func TestRenderMarkdown(t *testing.T) {
+	resolve := func(dest string, image bool) string {
+		if image {
+			return "/download/bucket/docs/" + dest
+		}
+		return "/preview/bucket/docs/" + dest
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "見出しと表",
+			source:   "# Title\n\n| a | b |\n|---|---|\n| 1 | 2 |\n",
+			contains: []string{`<h1 id="title">Title</h1>`, "<table>", "<td>1</td>"},
+		},
+		{
+			name:        "スクリプトは除去",
+			source:      "hello <script>alert(1)</script> <img src=x onerror=alert(1)>\n\n[x](javascript:alert(1))\n",
+			contains:    []string{"hello", `<img src="x"`},
+			notContains: []string{"<script>", "onerror", "javascript:"},
+		},
+		{
+			name:        "相対リンクを変換",
+			source:      "[guide](guide.md) ![logo](img/logo.png) [site](https://example.com/a) [top](#top)\n",
+			contains:    []string{`href="/preview/bucket/docs/guide.md"`, `src="/download/bucket/docs/img/logo.png"`, `href="https://example.com/a"`, `href="#top"`},
+			notContains: []string{`href="/preview/bucket/docs/https:`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderMarkdown([]byte(tt.source), resolve)
+			assert.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, string(result), s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, string(result), s)
+			}
+		})
+	}
+}
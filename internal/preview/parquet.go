@@ -0,0 +1,921 @@
+package preview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/sync/errgroup"
+)
+
+// parquetMagic is the magic number at the beginning and the end of a Parquet file.
+const parquetMagic = "PAR1"
+
+// Limits of a Parquet preview to protect the server from huge or malicious files.
+const (
+	maxParquetFooterBytes  = 16 << 20
+	maxParquetPageBytes    = 64 << 20
+	maxParquetHeaderBytes  = 1 << 20
+	maxParquetRowGroups    = 100
+	maxParquetColumns      = 50
+	maxParquetSchemaDepth  = 64
+	maxParquetDecimalScale = 1000
+)
+
+// Parquet physical types.
+const (
+	parquetBoolean = iota
+	parquetInt32
+	parquetInt64
+	parquetInt96
+	parquetFloat
+	parquetDouble
+	parquetByteArray
+	parquetFixedLenByteArray
+)
+
+// Parquet compression codecs.
+const (
+	parquetUncompressed = 0
+	parquetSnappy       = 1
+	parquetGzip         = 2
+	parquetZstd         = 6
+)
+
+// Parquet encodings that can be decoded.
+const (
+	parquetPlain           = 0
+	parquetPlainDictionary = 2
+	parquetRLEDictionary   = 8
+)
+
+// Parquet page types.
+const (
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+)
+
+var (
+	parquetTypeNames       = []string{"BOOLEAN", "INT32", "INT64", "INT96", "FLOAT", "DOUBLE", "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY"}
+	parquetRepetitionNames = []string{"required", "optional", "repeated"}
+	parquetCodecNames      = []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "BROTLI", "LZ4", "ZSTD", "LZ4_RAW"}
+	parquetEncodingNames   = []string{"PLAIN", "GROUP_VAR_INT", "PLAIN_DICTIONARY", "RLE", "BIT_PACKED",
+		"DELTA_BINARY_PACKED", "DELTA_LENGTH_BYTE_ARRAY", "DELTA_BYTE_ARRAY", "RLE_DICTIONARY", "BYTE_STREAM_SPLIT"}
+	parquetConvertedTypeNames = []string{"UTF8", "MAP", "MAP_KEY_VALUE", "LIST", "ENUM", "DECIMAL", "DATE",
+		"TIME_MILLIS", "TIME_MICROS", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "UINT_8", "UINT_16", "UINT_32", "UINT_64",
+		"INT_8", "INT_16", "INT_32", "INT_64", "JSON", "BSON", "INTERVAL"}
+	parquetLogicalTypeNames = map[int16]string{1: "STRING", 2: "MAP", 3: "LIST", 4: "ENUM", 5: "DECIMAL", 6: "DATE",
+		7: "TIME", 8: "TIMESTAMP", 10: "INTEGER", 11: "UNKNOWN", 12: "JSON", 13: "BSON", 14: "UUID", 15: "FLOAT16"}
+)
+
+// zstdDecoder is shared since it is safe for concurrent use with DecodeAll.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxParquetPageBytes))
+
+// ParquetFile is the metadata and the first rows of a Parquet file.
+type ParquetFile struct {
+	Version   int64
+	NumRows   int64
+	CreatedBy string
+	Schema    []ParquetField
+	RowGroups []ParquetRowGroup
+	// RowGroupsTruncated reports whether row groups after RowGroups are omitted.
+	RowGroupsTruncated bool
+	// Table is the first rows of the first row group.
+	Table *Table
+	// Notes explains the columns whose values are not shown.
+	Notes []string
+}
+
+// ParquetField is an element of the schema of a Parquet file.
+type ParquetField struct {
+	Name string
+	// Depth is the nesting level, 0 for top-level fields.
+	Depth      int
+	Type       string
+	Repetition string
+}
+
+// ParquetRowGroup is the statistics of a row group of a Parquet file.
+type ParquetRowGroup struct {
+	NumRows       int64
+	TotalByteSize int64
+	Columns       []ParquetColumnChunk
+}
+
+// ParquetColumnChunk is the statistics of a column in a row group.
+type ParquetColumnChunk struct {
+	Path             string
+	Codec            string
+	Encodings        string
+	NumValues        int64
+	CompressedSize   int64
+	UncompressedSize int64
+	NullCount        string
+	Min              string
+	Max              string
+}
+
+// parquetColumn is a leaf of the schema, which is stored as a column chunk in each row group.
+type parquetColumn struct {
+	path          string
+	element       thriftFields
+	maxDefinition int
+	maxRepetition int
+}
+
+// ReadParquet reads the footer of a Parquet file of the size with range reads and decodes up to maxRows rows
+// of the first row group. Only the beginning of each column chunk needed for the rows is read.
+// Values of nested columns and of unsupported encodings or codecs are not shown, which is explained in Notes.
+func ReadParquet(r io.ReaderAt, size int64, maxRows int) (*ParquetFile, error) {
+	metadata, err := readParquetFooter(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &ParquetFile{
+		Version:   metadata.int(1),
+		NumRows:   metadata.int(3),
+		CreatedBy: metadata.string(6),
+	}
+	columns, err := file.readSchema(metadata.list(2))
+	if err != nil {
+		return nil, err
+	}
+
+	rowGroups := metadata.list(4)
+	for i, rowGroup := range rowGroups {
+		if i == maxParquetRowGroups {
+			file.RowGroupsTruncated = true
+			break
+		}
+		file.RowGroups = append(file.RowGroups, newParquetRowGroup(asFields(rowGroup), columns))
+	}
+
+	file.Table = &Table{}
+	for _, column := range columns {
+		file.Table.Header = append(file.Table.Header, column.path)
+	}
+	if len(rowGroups) > 0 {
+		file.readRows(r, size, asFields(rowGroups[0]), columns, maxRows)
+	}
+	return file, nil
+}
+
+// readParquetFooter reads and decodes the FileMetaData at the end of the file.
+func readParquetFooter(r io.ReaderAt, size int64) (thriftFields, error) {
+	if size < int64(2*len(parquetMagic)+4) {
+		return nil, errors.New("not a Parquet file: too small")
+	}
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read Parquet footer: %w", err)
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, errors.New("not a Parquet file: invalid magic number")
+	}
+	length := int64(binary.LittleEndian.Uint32(tail))
+	if length > maxParquetFooterBytes || length > size-8-int64(len(parquetMagic)) {
+		return nil, fmt.Errorf("invalid Parquet footer length %d", length)
+	}
+
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-8-length); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read Parquet footer: %w", err)
+	}
+	metadata, err := (&thriftReader{data: footer}).readStruct(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Parquet footer: %w", err)
+	}
+	return metadata, nil
+}
+
+// readSchema flattens the schema elements, which are stored depth-first with the number of children,
+// and returns the leaf columns.
+func (f *ParquetFile) readSchema(elements []interface{}) ([]*parquetColumn, error) {
+	var columns []*parquetColumn
+	pos := 0
+	var walk func(depth int, path []string, definition, repetition int) error
+	walk = func(depth int, path []string, definition, repetition int) error {
+		if pos >= len(elements) {
+			return errors.New("invalid Parquet schema: missing elements")
+		}
+		if depth >= maxParquetSchemaDepth {
+			return errors.New("invalid Parquet schema: nested too deeply")
+		}
+		element := asFields(elements[pos])
+		pos++
+
+		switch element.int(3) {
+		case 1:
+			definition++
+		case 2:
+			definition++
+			repetition++
+		}
+		path = append(path, element.string(4))
+		f.Schema = append(f.Schema, ParquetField{
+			Name:       element.string(4),
+			Depth:      depth,
+			Type:       parquetTypeName(element),
+			Repetition: enumName(parquetRepetitionNames, element.int(3)),
+		})
+
+		if !element.has(5) {
+			columns = append(columns, &parquetColumn{
+				path:          strings.Join(path, "."),
+				element:       element,
+				maxDefinition: definition,
+				maxRepetition: repetition,
+			})
+			return nil
+		}
+		for i := int64(0); i < element.int(5); i++ {
+			if err := walk(depth+1, path, definition, repetition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(elements) == 0 {
+		return nil, errors.New("invalid Parquet schema: no root")
+	}
+	// The root is the message itself, which is not shown
+	root := asFields(elements[0])
+	pos = 1
+	for i := int64(0); i < root.int(5); i++ {
+		if err := walk(0, nil, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// newParquetRowGroup returns the statistics of the row group.
+func newParquetRowGroup(rowGroup thriftFields, columns []*parquetColumn) ParquetRowGroup {
+	result := ParquetRowGroup{
+		NumRows:       rowGroup.int(3),
+		TotalByteSize: rowGroup.int(2),
+	}
+	for i, chunk := range rowGroup.list(1) {
+		meta := asFields(chunk).strct(3)
+		var encodings []string
+		for _, encoding := range meta.list(2) {
+			encodings = append(encodings, enumName(parquetEncodingNames, asInt(encoding)))
+		}
+		stats := ParquetColumnChunk{
+			Codec:            enumName(parquetCodecNames, meta.int(4)),
+			Encodings:        strings.Join(encodings, ", "),
+			NumValues:        meta.int(5),
+			UncompressedSize: meta.int(6),
+			CompressedSize:   meta.int(7),
+		}
+		var path []string
+		for _, name := range meta.list(3) {
+			b, _ := name.([]byte)
+			path = append(path, string(b))
+		}
+		stats.Path = strings.Join(path, ".")
+
+		statistics := meta.strct(12)
+		if statistics.has(3) {
+			stats.NullCount = strconv.FormatInt(statistics.int(3), 10)
+		}
+		if i < len(columns) {
+			// min_value and max_value supersede the deprecated min and max
+			stats.Min = columns[i].formatStatistic(statistics, 6, 2)
+			stats.Max = columns[i].formatStatistic(statistics, 5, 1)
+		}
+		result.Columns = append(result.Columns, stats)
+	}
+	return result
+}
+
+// formatStatistic formats the PLAIN-encoded statistic value of the field, or the deprecated field if it is missing.
+func (c *parquetColumn) formatStatistic(statistics thriftFields, field, deprecatedField int16) string {
+	value := statistics.bytes(field)
+	if !statistics.has(field) {
+		if !statistics.has(deprecatedField) {
+			return ""
+		}
+		value = statistics.bytes(deprecatedField)
+	}
+	if c.element.int(1) == parquetByteArray {
+		// Statistics of byte arrays have no length prefix
+		return c.format(value)
+	}
+	values, err := c.decodePlain(value, 1)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	return c.format(values[0])
+}
+
+// readRows decodes the first rows of the row group of the file of the size into the table,
+// reading the column chunks concurrently.
+func (f *ParquetFile) readRows(r io.ReaderAt, size int64, rowGroup thriftFields, columns []*parquetColumn, maxRows int) {
+	if rowGroup.int(3) < 0 {
+		f.Notes = append(f.Notes, fmt.Sprintf("invalid number of rows %d in the row group", rowGroup.int(3)))
+		return
+	}
+	numRows := int(min(rowGroup.int(3), int64(max(maxRows, 0))))
+	chunks := rowGroup.list(1)
+	values := make([][]interface{}, len(columns))
+	errs := make([]error, len(columns))
+
+	var group errgroup.Group
+	group.SetLimit(8)
+	for i, column := range columns {
+		switch {
+		case i >= maxParquetColumns:
+			errs[i] = fmt.Errorf("only the first %d columns are previewed", maxParquetColumns)
+		case i >= len(chunks):
+			errs[i] = errors.New("column chunk is missing")
+		case column.maxRepetition > 0:
+			errs[i] = errors.New("nested columns are not previewed")
+		default:
+			meta := asFields(chunks[i]).strct(3)
+			group.Go(func() error {
+				values[i], errs[i] = column.readValues(r, size, meta, numRows)
+				return nil
+			})
+		}
+	}
+	_ = group.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			f.Notes = append(f.Notes, fmt.Sprintf("%s: %v", columns[i].path, err))
+		}
+	}
+	for row := 0; row < numRows; row++ {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if row < len(values[i]) && values[i][row] != nil {
+				cells[i] = column.format(values[i][row])
+			}
+		}
+		f.Table.Rows = append(f.Table.Rows, cells)
+	}
+	f.Table.Truncated = int64(numRows) < f.NumRows
+}
+
+// readValues reads the pages of the column chunk until n values are decoded. Null values are nil.
+// The chunk must lie within the file of the size.
+func (c *parquetColumn) readValues(r io.ReaderAt, size int64, meta thriftFields, n int) ([]interface{}, error) {
+	codec := meta.int(4)
+	offset := meta.int(9)
+	if dictionaryOffset := meta.int(11); dictionaryOffset > 0 && dictionaryOffset < offset {
+		offset = dictionaryOffset
+	}
+	length := meta.int(7)
+	if offset < 0 || length < 0 || length > size-offset {
+		return nil, fmt.Errorf("invalid column chunk of %d bytes at offset %d", length, offset)
+	}
+	end := offset + length
+
+	var dictionary []interface{}
+	values := make([]interface{}, 0, n)
+	for offset < end && len(values) < n {
+		header, body, next, err := readParquetPage(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+
+		switch header.int(1) {
+		case parquetDictionaryPage:
+			page, err := decompressBlock(codec, body, header.int(2))
+			if err != nil {
+				return nil, err
+			}
+			dictionary, err = c.decodePlain(page, header.strct(7).int(1))
+			if err != nil {
+				return nil, err
+			}
+		case parquetDataPage:
+			page, err := decompressBlock(codec, body, header.int(2))
+			if err != nil {
+				return nil, err
+			}
+			pageHeader := header.strct(5)
+			pageN, err := pageValueCount(pageHeader.int(1), n-len(values))
+			if err != nil {
+				return nil, err
+			}
+			pageValues, err := c.decodeDataPage(page, pageN, pageHeader.int(2), dictionary)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, pageValues...)
+		case parquetDataPageV2:
+			pageHeader := header.strct(8)
+			pageN, err := pageValueCount(pageHeader.int(1), n-len(values))
+			if err != nil {
+				return nil, err
+			}
+			pageValues, err := c.decodeDataPageV2(body, pageHeader, pageN, codec, header.int(2), dictionary)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, pageValues...)
+		}
+	}
+	return values[:min(len(values), n)], nil
+}
+
+// pageValueCount returns the number of values to decode from a data page of numValues values
+// when only the first remaining values are needed, so that no more than the previewed rows are allocated.
+func pageValueCount(numValues int64, remaining int) (int, error) {
+	if numValues < 0 {
+		return 0, fmt.Errorf("invalid number of values %d in the page", numValues)
+	}
+	return int(min(numValues, int64(remaining))), nil
+}
+
+// readParquetPage reads the page header at the offset and the page body, and returns the offset of the next page.
+func readParquetPage(r io.ReaderAt, offset, end int64) (header thriftFields, body []byte, next int64, err error) {
+	window := int64(64 << 10)
+	for {
+		buf := make([]byte, min(window, end-offset))
+		n, err := r.ReadAt(buf, offset)
+		if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+			return nil, nil, 0, fmt.Errorf("failed to read page: %w", err)
+		}
+		buf = buf[:n]
+
+		reader := &thriftReader{data: buf}
+		header, err = reader.readStruct(0)
+		if errors.Is(err, errThriftTruncated) && int64(len(buf)) < end-offset && window < maxParquetHeaderBytes {
+			// The header has large statistics
+			window *= 16
+			continue
+		}
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to decode page header: %w", err)
+		}
+
+		size := header.int(3)
+		bodyOffset := offset + int64(reader.pos)
+		if size < 0 || size > maxParquetPageBytes || size > end-bodyOffset {
+			return nil, nil, 0, fmt.Errorf("invalid page size %d", size)
+		}
+		if int64(reader.pos)+size <= int64(len(buf)) {
+			body = buf[reader.pos : int64(reader.pos)+size]
+		} else {
+			body = make([]byte, size)
+			if n, err := r.ReadAt(body, bodyOffset); err != nil && !(errors.Is(err, io.EOF) && int64(n) == size) {
+				return nil, nil, 0, fmt.Errorf("failed to read page: %w", err)
+			}
+		}
+		return header, body, bodyOffset + size, nil
+	}
+}
+
+// decodeDataPage decodes the first n values including nulls of a decompressed DATA_PAGE.
+func (c *parquetColumn) decodeDataPage(page []byte, n int, encoding int64, dictionary []interface{}) ([]interface{}, error) {
+	var definitions []int32
+	if c.maxDefinition > 0 {
+		if len(page) < 4 {
+			return nil, errors.New("invalid data page: missing definition levels")
+		}
+		length := int(binary.LittleEndian.Uint32(page))
+		if length > len(page)-4 {
+			return nil, errors.New("invalid data page: invalid definition levels length")
+		}
+		var err error
+		definitions, err = decodeHybrid(page[4:4+length], bits.Len(uint(c.maxDefinition)), n)
+		if err != nil {
+			return nil, err
+		}
+		page = page[4+length:]
+	}
+	return c.decodeValues(page, encoding, n, definitions, dictionary)
+}
+
+// decodeDataPageV2 decodes the first n values including nulls of a DATA_PAGE_V2, whose levels are not compressed.
+func (c *parquetColumn) decodeDataPageV2(body []byte, header thriftFields, n int, codec, size int64, dictionary []interface{}) ([]interface{}, error) {
+	repetitionLength, definitionLength := header.int(6), header.int(5)
+	if repetitionLength < 0 || definitionLength < 0 || repetitionLength > int64(len(body)) || definitionLength > int64(len(body))-repetitionLength {
+		return nil, errors.New("invalid data page: invalid levels length")
+	}
+	var definitions []int32
+	if c.maxDefinition > 0 {
+		var err error
+		definitions, err = decodeHybrid(body[repetitionLength:repetitionLength+definitionLength], bits.Len(uint(c.maxDefinition)), n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := body[repetitionLength+definitionLength:]
+	if header.bool(7, true) {
+		var err error
+		page, err = decompressBlock(codec, page, size-repetitionLength-definitionLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.decodeValues(page, header.int(4), n, definitions, dictionary)
+}
+
+// decodeValues decodes the values of a data page and places nil where the definition level indicates null.
+func (c *parquetColumn) decodeValues(data []byte, encoding int64, n int, definitions []int32, dictionary []interface{}) ([]interface{}, error) {
+	nonNull := n
+	if definitions != nil {
+		nonNull = 0
+		for _, level := range definitions {
+			if int(level) == c.maxDefinition {
+				nonNull++
+			}
+		}
+	}
+
+	var values []interface{}
+	switch encoding {
+	case parquetPlain:
+		var err error
+		values, err = c.decodePlain(data, int64(nonNull))
+		if err != nil {
+			return nil, err
+		}
+	case parquetPlainDictionary, parquetRLEDictionary:
+		if len(data) == 0 {
+			if nonNull > 0 {
+				return nil, errors.New("invalid data page: missing dictionary indices")
+			}
+			break
+		}
+		indices, err := decodeHybrid(data[1:], int(data[0]), nonNull)
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, len(indices))
+		for i, index := range indices {
+			if index < 0 || int(index) >= len(dictionary) {
+				return nil, fmt.Errorf("dictionary index %d is out of range", index)
+			}
+			values[i] = dictionary[index]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s", enumName(parquetEncodingNames, encoding))
+	}
+
+	if definitions == nil {
+		return values, nil
+	}
+	result := make([]interface{}, n)
+	next := 0
+	for i, level := range definitions {
+		if int(level) == c.maxDefinition && next < len(values) {
+			result[i] = values[next]
+			next++
+		}
+	}
+	return result, nil
+}
+
+// decodePlain decodes n PLAIN-encoded values of the column's physical type.
+// n is checked against the length of data since it may come from an untrusted page header.
+func (c *parquetColumn) decodePlain(data []byte, n int64) ([]interface{}, error) {
+	errTruncated := errors.New("invalid PLAIN values: unexpected end of data")
+	// Each value takes at least a bit for BOOLEAN and a byte for the other types
+	maxValues := int64(len(data))
+	if c.element.int(1) == parquetBoolean {
+		maxValues *= 8
+	}
+	if n < 0 || n > maxValues {
+		return nil, fmt.Errorf("invalid number of PLAIN values %d for %d bytes", n, len(data))
+	}
+	values := make([]interface{}, 0, n)
+	pos := 0
+	fixed := func(size int) ([]byte, error) {
+		if size < 0 || size > len(data)-pos {
+			return nil, errTruncated
+		}
+		b := data[pos : pos+size]
+		pos += size
+		return b, nil
+	}
+
+	for i := 0; i < int(n); i++ {
+		switch c.element.int(1) {
+		case parquetBoolean:
+			if i/8 >= len(data) {
+				return nil, errTruncated
+			}
+			values = append(values, data[i/8]&(1<<(i%8)) != 0)
+		case parquetInt32:
+			b, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, int32(binary.LittleEndian.Uint32(b)))
+		case parquetInt64:
+			b, err := fixed(8)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, int64(binary.LittleEndian.Uint64(b)))
+		case parquetInt96:
+			b, err := fixed(12)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, int96(b))
+		case parquetFloat:
+			b, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case parquetDouble:
+			b, err := fixed(8)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		case parquetByteArray:
+			b, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			b, err = fixed(int(binary.LittleEndian.Uint32(b)))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, b)
+		case parquetFixedLenByteArray:
+			b, err := fixed(int(c.element.int(2)))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, b)
+		default:
+			return nil, fmt.Errorf("unknown physical type %d", c.element.int(1))
+		}
+	}
+	return values, nil
+}
+
+// int96 is a legacy timestamp of nanoseconds in the day followed by the Julian day.
+type int96 []byte
+
+// time returns the timestamp of the INT96 value.
+func (v int96) time() time.Time {
+	nanos := int64(binary.LittleEndian.Uint64(v[:8]))
+	julianDay := int64(binary.LittleEndian.Uint32(v[8:]))
+	const unixEpochJulianDay = 2440588
+	return time.Unix((julianDay-unixEpochJulianDay)*86400, nanos).UTC()
+}
+
+// decodeHybrid decodes n values of the RLE/bit-packing hybrid encoding used for levels and dictionary indices.
+func decodeHybrid(data []byte, bitWidth int, n int) ([]int32, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid number of values %d", n)
+	}
+	values := make([]int32, 0, n)
+	pos := 0
+	for len(values) < n {
+		header, size := binary.Uvarint(data[pos:])
+		if size <= 0 {
+			return nil, errors.New("invalid RLE/bit-packed data: unexpected end of data")
+		}
+		pos += size
+
+		if header&1 == 1 {
+			// Bit-packed groups of 8 values, least significant bit first.
+			// The number of groups is checked before multiplying so that it cannot overflow.
+			groups := header >> 1
+			if bitWidth > 0 && groups > uint64((len(data)-pos)/bitWidth) {
+				return nil, errors.New("invalid RLE/bit-packed data: unexpected end of data")
+			}
+			length := 0
+			if bitWidth > 0 {
+				length = int(groups) * bitWidth
+			}
+			count := int(min(groups, uint64(n))) * 8
+			packed := data[pos : pos+length]
+			pos += length
+			for i := 0; i < count && len(values) < n; i++ {
+				var value int32
+				for b := 0; b < bitWidth; b++ {
+					bit := i*bitWidth + b
+					if packed[bit/8]&(1<<(bit%8)) != 0 {
+						value |= 1 << b
+					}
+				}
+				values = append(values, value)
+			}
+		} else {
+			// A run of the same value
+			count := int(min(header>>1, uint64(n)))
+			width := (bitWidth + 7) / 8
+			if width > len(data)-pos {
+				return nil, errors.New("invalid RLE/bit-packed data: unexpected end of data")
+			}
+			var value int32
+			for b := 0; b < width; b++ {
+				value |= int32(data[pos+b]) << (8 * b)
+			}
+			pos += width
+			for i := 0; i < count && len(values) < n; i++ {
+				values = append(values, value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// decompressBlock decompresses a whole page with the Parquet codec into at most size bytes.
+func decompressBlock(codec int64, data []byte, size int64) ([]byte, error) {
+	if size < 0 || size > maxParquetPageBytes {
+		return nil, fmt.Errorf("invalid uncompressed page size %d", size)
+	}
+	switch codec {
+	case parquetUncompressed:
+		return data, nil
+	case parquetSnappy:
+		if n, err := snappy.DecodedLen(data); err != nil || int64(n) > size {
+			return nil, errors.New("invalid snappy page")
+		}
+		return snappy.Decode(nil, data)
+	case parquetGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(r, size))
+	case parquetZstd:
+		page, err := zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(page)) > size {
+			return nil, errors.New("invalid zstd page")
+		}
+		return page, nil
+	}
+	return nil, fmt.Errorf("unsupported codec %s", enumName(parquetCodecNames, codec))
+}
+
+// format returns the value as a string according to the logical type of the column.
+func (c *parquetColumn) format(value interface{}) string {
+	converted := int64(-1)
+	if c.element.has(6) {
+		converted = c.element.int(6)
+	}
+	logical := c.element.strct(10)
+
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return c.formatInt(int64(v), 32, converted, logical)
+	case int64:
+		return c.formatInt(v, 64, converted, logical)
+	case int96:
+		return v.time().Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		switch {
+		case converted == 5 || logical.has(5):
+			return formatDecimal(new(big.Int).SetBytes(v), len(v), c.scale())
+		case logical.has(14) && len(v) == 16:
+			h := hex.EncodeToString(v)
+			return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		case utf8.Valid(v):
+			return string(v)
+		}
+		return "0x" + hex.EncodeToString(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// formatInt formats an INT32 or INT64 value with its logical type.
+func (c *parquetColumn) formatInt(v int64, bitSize int, converted int64, logical thriftFields) string {
+	switch {
+	case converted == 5 || logical.has(5):
+		return formatDecimal(big.NewInt(v), 0, c.scale())
+	case converted == 6 || logical.has(6):
+		return time.Unix(v*86400, 0).UTC().Format(time.DateOnly)
+	case converted == 9:
+		return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+	case converted == 10:
+		return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+	case logical.has(8):
+		switch unit := logical.strct(8).strct(2); {
+		case unit.has(1):
+			return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+		case unit.has(2):
+			return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+		default:
+			return time.Unix(0, v).UTC().Format(time.RFC3339Nano)
+		}
+	case (converted >= 11 && converted <= 14) || (logical.has(10) && !logical.strct(10).bool(2, true)):
+		if bitSize == 32 {
+			return strconv.FormatUint(uint64(uint32(v)), 10)
+		}
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// scale returns the scale of a DECIMAL column.
+func (c *parquetColumn) scale() int {
+	if logical := c.element.strct(10); logical.has(5) {
+		return int(logical.strct(5).int(1))
+	}
+	return int(c.element.int(7))
+}
+
+// formatDecimal formats an unscaled decimal. A non-zero length means the value is big-endian two's complement of length bytes.
+func formatDecimal(unscaled *big.Int, length int, scale int) string {
+	if length > 0 && unscaled.Bit(length*8-1) == 1 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(length*8)))
+	}
+	s := new(big.Int).Abs(unscaled).String()
+	if scale > maxParquetDecimalScale {
+		// Padding the value with so many zeros would only waste memory
+		s += "e-" + strconv.Itoa(scale)
+	} else if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if unscaled.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// parquetTypeName returns the type of the schema element with its logical or converted type.
+func parquetTypeName(element thriftFields) string {
+	var name string
+	if element.has(1) {
+		name = enumName(parquetTypeNames, element.int(1))
+		if element.int(1) == parquetFixedLenByteArray {
+			name += "(" + strconv.FormatInt(element.int(2), 10) + ")"
+		}
+	}
+	annotation := ""
+	for id := range element.strct(10) {
+		annotation = parquetLogicalTypeNames[id]
+	}
+	if annotation == "" && element.has(6) {
+		annotation = enumName(parquetConvertedTypeNames, element.int(6))
+	}
+	if annotation != "" {
+		if name == "" {
+			return annotation
+		}
+		return name + " (" + annotation + ")"
+	}
+	if name == "" {
+		return "group"
+	}
+	return name
+}
+
+// enumName returns the name of the enum value, or the number if it is unknown.
+func enumName(names []string, value int64) string {
+	if value >= 0 && value < int64(len(names)) {
+		return names[value]
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// asFields returns the value as a struct, or an empty struct if it is not.
+func asFields(value interface{}) thriftFields {
+	if fields, ok := value.(thriftFields); ok {
+		return fields
+	}
+	return thriftFields{}
+}
+
+// asInt returns the value as an integer, or 0 if it is not.
+func asInt(value interface{}) int64 {
+	v, _ := value.(int64)
+	return v
+}
@@ -0,0 +1,431 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+// parquetTestColumn is a column chunk of the Parquet file built by buildParquet.
+type parquetTestColumn struct {
+	physicalType int64
+	path         []string
+	codec        int64
+	pages        [][]byte
+	dictionary   bool
+	numValues    int64
+	stats        func(w *thriftWriter)
+}
+
+// buildParquet builds a Parquet file with a row group of the columns and the schema elements written by schema.
+func buildParquet(numRows int64, schema []func(w *thriftWriter), columns []parquetTestColumn) []byte {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	offsets := make([]int64, len(columns))
+	sizes := make([]int64, len(columns))
+	for i, column := range columns {
+		offsets[i] = int64(file.Len())
+		for _, page := range column.pages {
+			file.Write(page)
+		}
+		sizes[i] = int64(file.Len()) - offsets[i]
+	}
+
+	w := &thriftWriter{}
+	w.begin()
+	w.i32(1, 2)
+	w.list(2, thriftStruct, len(schema), func(i int) { schema[i](w) })
+	w.i64(3, numRows)
+	w.list(4, thriftStruct, 1, func(int) {
+		w.begin()
+		w.list(1, thriftStruct, len(columns), func(i int) {
+			column := columns[i]
+			w.begin()
+			w.i64(2, offsets[i])
+			w.strct(3, func() {
+				w.i32(1, column.physicalType)
+				w.list(2, thriftI32, 1, func(int) { w.varint(parquetPlain) })
+				w.list(3, thriftBinary, len(column.path), func(j int) { w.binary([]byte(column.path[j])) })
+				w.i32(4, column.codec)
+				w.i64(5, column.numValues)
+				w.i64(6, sizes[i])
+				w.i64(7, sizes[i])
+				w.i64(9, offsets[i])
+				if column.dictionary {
+					w.i64(11, offsets[i])
+				}
+				if column.stats != nil {
+					w.strct(12, func() { column.stats(w) })
+				}
+			})
+			w.end()
+		})
+		w.i64(2, sizes[0])
+		w.i64(3, numRows)
+		w.end()
+	})
+	w.bytes(6, []byte("polybuckets test"))
+	w.end()
+
+	file.Write(w.buf.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(w.buf.Len())))
+	file.WriteString(parquetMagic)
+	return file.Bytes()
+}
+
+// schemaElement writes a schema element as a list element.
+func schemaElement(w *thriftWriter, physicalType, repetition int64, name string, numChildren int64, fn func()) {
+	w.begin()
+	if physicalType >= 0 {
+		w.i32(1, physicalType)
+	}
+	if repetition >= 0 {
+		w.i32(3, repetition)
+	}
+	w.bytes(4, []byte(name))
+	if numChildren > 0 {
+		w.i32(5, numChildren)
+	}
+	if fn != nil {
+		fn()
+	}
+	w.end()
+}
+
+// parquetPage builds a page with the header written by header.
+func parquetPage(header func(w *thriftWriter), body []byte) []byte {
+	w := &thriftWriter{}
+	w.begin()
+	header(w)
+	w.end()
+	return append(w.buf.Bytes(), body...)
+}
+
+// plainInts encodes INT32 or INT64 values with PLAIN.
+func plainInts(size int, values ...int64) []byte {
+	var b []byte
+	for _, v := range values {
+		if size == 4 {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		} else {
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		}
+	}
+	return b
+}
+
+// plainStrings encodes BYTE_ARRAY values with PLAIN.
+func plainStrings(values ...string) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+// testParquetFile builds a Parquet file with a required INT64 column, an optional dictionary-encoded string column,
+// an optional snappy-compressed DECIMAL column in a DATA_PAGE_V2 and a repeated column.
+func testParquetFile() []byte {
+	// id: 1, 2, 3
+	idValues := plainInts(8, 1, 2, 3)
+	idPage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDataPage)
+		w.i32(2, int64(len(idValues)))
+		w.i32(3, int64(len(idValues)))
+		w.strct(5, func() {
+			w.i32(1, 3)
+			w.i32(2, parquetPlain)
+			w.i32(3, 3)
+			w.i32(4, 3)
+		})
+	}, idValues)
+
+	// name: "alice", null, "bob" with a dictionary
+	dictionaryValues := plainStrings("alice", "bob")
+	dictionaryPage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDictionaryPage)
+		w.i32(2, int64(len(dictionaryValues)))
+		w.i32(3, int64(len(dictionaryValues)))
+		w.strct(7, func() {
+			w.i32(1, 2)
+			w.i32(2, parquetPlainDictionary)
+		})
+	}, dictionaryValues)
+	// Definition levels 1, 0, 1 and indices 0, 1, bit-packed
+	nameValues := []byte{2, 0, 0, 0, 3, 0b101, 1, 3, 0b10}
+	namePage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDataPage)
+		w.i32(2, int64(len(nameValues)))
+		w.i32(3, int64(len(nameValues)))
+		w.strct(5, func() {
+			w.i32(1, 3)
+			w.i32(2, parquetRLEDictionary)
+			w.i32(3, 3)
+			w.i32(4, 3)
+		})
+	}, nameValues)
+
+	// price: 12.34, null, -0.05 with definition levels as RLE runs
+	levels := []byte{2, 1, 2, 0, 2, 1}
+	priceValues := plainInts(4, 1234, -5)
+	compressed := snappy.Encode(nil, priceValues)
+	pricePage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDataPageV2)
+		w.i32(2, int64(len(levels)+len(priceValues)))
+		w.i32(3, int64(len(levels)+len(compressed)))
+		w.strct(8, func() {
+			w.i32(1, 3)
+			w.i32(2, 1)
+			w.i32(3, 3)
+			w.i32(4, parquetPlain)
+			w.i32(5, int64(len(levels)))
+			w.i32(6, 0)
+			w.bool(7, true)
+		})
+	}, append(levels, compressed...))
+
+	schema := []func(w *thriftWriter){
+		func(w *thriftWriter) { schemaElement(w, -1, -1, "schema", 4, nil) },
+		func(w *thriftWriter) { schemaElement(w, parquetInt64, 0, "id", 0, nil) },
+		func(w *thriftWriter) { schemaElement(w, parquetByteArray, 1, "name", 0, func() { w.i32(6, 0) }) },
+		func(w *thriftWriter) {
+			schemaElement(w, parquetInt32, 1, "price", 0, func() {
+				w.i32(6, 5)
+				w.i32(7, 2)
+				w.i32(8, 9)
+			})
+		},
+		func(w *thriftWriter) { schemaElement(w, parquetByteArray, 2, "tags", 0, nil) },
+	}
+
+	return buildParquet(3, schema, []parquetTestColumn{
+		{physicalType: parquetInt64, path: []string{"id"}, pages: [][]byte{idPage}, numValues: 3, stats: func(w *thriftWriter) {
+			w.bytes(5, plainInts(8, 3))
+			w.bytes(6, plainInts(8, 1))
+		}},
+		{physicalType: parquetByteArray, path: []string{"name"}, pages: [][]byte{dictionaryPage, namePage}, dictionary: true, numValues: 3, stats: func(w *thriftWriter) {
+			w.i64(3, 1)
+			w.bytes(5, []byte("bob"))
+			w.bytes(6, []byte("alice"))
+		}},
+		{physicalType: parquetInt32, path: []string{"price"}, codec: parquetSnappy, pages: [][]byte{pricePage}, numValues: 3},
+		{physicalType: parquetByteArray, path: []string{"tags"}, numValues: 0},
+	})
+}
+
+// TestReadParquet tests reading the metadata and the first rows of a Parquet file
+func TestReadParquet(t *testing.T) {
+	data := testParquetFile()
+
+	file, err := ReadParquet(bytes.NewReader(data), int64(len(data)), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), file.NumRows)
+	assert.Equal(t, "polybuckets test", file.CreatedBy)
+	assert.Equal(t, []ParquetField{
+		{Name: "id", Type: "INT64", Repetition: "required"},
+		{Name: "name", Type: "BYTE_ARRAY (UTF8)", Repetition: "optional"},
+		{Name: "price", Type: "INT32 (DECIMAL)", Repetition: "optional"},
+		{Name: "tags", Type: "BYTE_ARRAY", Repetition: "repeated"},
+	}, file.Schema)
+
+	assert.Len(t, file.RowGroups, 1)
+	assert.Equal(t, int64(3), file.RowGroups[0].NumRows)
+	assert.Equal(t, "1", file.RowGroups[0].Columns[0].Min)
+	assert.Equal(t, "3", file.RowGroups[0].Columns[0].Max)
+	assert.Equal(t, "alice", file.RowGroups[0].Columns[1].Min)
+	assert.Equal(t, "1", file.RowGroups[0].Columns[1].NullCount)
+	assert.Equal(t, "SNAPPY", file.RowGroups[0].Columns[2].Codec)
+
+	assert.Equal(t, &Table{
+		Header: []string{"id", "name", "price", "tags"},
+		Rows: [][]string{
+			{"1", "alice", "12.34", ""},
+			{"2", "", "", ""},
+			{"3", "bob", "-0.05", ""},
+		},
+	}, file.Table)
+	assert.Equal(t, []string{"tags: nested columns are not previewed"}, file.Notes)
+
+	// The number of rows is limited
+	file, err = ReadParquet(bytes.NewReader(data), int64(len(data)), 2)
+	assert.NoError(t, err)
+	assert.Len(t, file.Table.Rows, 2)
+	assert.True(t, file.Table.Truncated)
+}
+
+// TestReadParquet_Error tests that invalid files are reported
+func TestReadParquet_Error(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		expectedErr string
+	}{
+		{name: "小さすぎる", data: []byte("PAR1"), expectedErr: "too small"},
+		{name: "マジックナンバーが不正", data: []byte("PAR1\x00\x00\x00\x00\x00\x00\x00\x00PAR2"), expectedErr: "invalid magic number"},
+		{name: "フッター長が不正", data: []byte("PAR1\x00\x00\x00\x00\xff\x00\x00\x00PAR1"), expectedErr: "invalid Parquet footer length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadParquet(bytes.NewReader(tt.data), int64(len(tt.data)), 10)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+// malformedParquet builds a Parquet file with a required INT64 column of a dictionary page and a data page
+// with the dictionary indices, whose numbers of values and rows may be invalid.
+func malformedParquet(numRows, dictionaryValues, dataValues int64, indices []byte) []byte {
+	dictionary := plainInts(8, 42, 43)
+	dictionaryPage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDictionaryPage)
+		w.i32(2, int64(len(dictionary)))
+		w.i32(3, int64(len(dictionary)))
+		w.strct(7, func() {
+			w.i32(1, dictionaryValues)
+			w.i32(2, parquetPlainDictionary)
+		})
+	}, dictionary)
+	dataPage := parquetPage(func(w *thriftWriter) {
+		w.i32(1, parquetDataPage)
+		w.i32(2, int64(len(indices)))
+		w.i32(3, int64(len(indices)))
+		w.strct(5, func() {
+			w.i32(1, dataValues)
+			w.i32(2, parquetRLEDictionary)
+			w.i32(3, 3)
+			w.i32(4, 3)
+		})
+	}, indices)
+
+	schema := []func(w *thriftWriter){
+		func(w *thriftWriter) { schemaElement(w, -1, -1, "schema", 1, nil) },
+		func(w *thriftWriter) { schemaElement(w, parquetInt64, 0, "id", 0, nil) },
+	}
+	return buildParquet(numRows, schema, []parquetTestColumn{
+		{physicalType: parquetInt64, path: []string{"id"}, pages: [][]byte{dictionaryPage, dataPage}, dictionary: true, numValues: dataValues},
+	})
+}
+
+// TestReadParquet_Malformed tests that counts from malformed headers neither panic nor allocate beyond the input
+func TestReadParquet_Malformed(t *testing.T) {
+	// Bit width 1 and a run of the index 1
+	run := []byte{1, 3 << 1, 1}
+	// Bit width 1 and a run so long that it would not fit in memory
+	hugeRun := binary.AppendUvarint([]byte{1}, math.MaxUint64-1)
+	hugeRun = append(hugeRun, 1)
+	// Bit width 8 and bit-packed groups whose size would overflow
+	hugeGroups := binary.AppendUvarint([]byte{8}, math.MaxUint64)
+
+	tests := []struct {
+		name          string
+		data          []byte
+		expectedRows  [][]string
+		expectedNotes []string
+	}{
+		{
+			name:          "辞書の値の数が負",
+			data:          malformedParquet(3, -1, 3, run),
+			expectedRows:  [][]string{{""}, {""}, {""}},
+			expectedNotes: []string{"id: invalid number of PLAIN values -1 for 16 bytes"},
+		},
+		{
+			name:          "辞書の値の数がデータより多い",
+			data:          malformedParquet(3, math.MaxInt32, 3, run),
+			expectedRows:  [][]string{{""}, {""}, {""}},
+			expectedNotes: []string{"id: invalid number of PLAIN values 2147483647 for 16 bytes"},
+		},
+		{
+			name:          "ページの値の数が負",
+			data:          malformedParquet(3, 2, -1, run),
+			expectedRows:  [][]string{{""}, {""}, {""}},
+			expectedNotes: []string{"id: invalid number of values -1 in the page"},
+		},
+		{
+			name:         "ページの値の数と連長が巨大",
+			data:         malformedParquet(3, 2, math.MaxInt32, hugeRun),
+			expectedRows: [][]string{{"43"}, {"43"}, {"43"}},
+		},
+		{
+			name:          "ビットパックのグループ数が巨大",
+			data:          malformedParquet(3, 2, 3, hugeGroups),
+			expectedRows:  [][]string{{""}, {""}, {""}},
+			expectedNotes: []string{"id: invalid RLE/bit-packed data: unexpected end of data"},
+		},
+		{
+			name:          "行グループの行数が負",
+			data:          malformedParquet(-1, 2, 3, run),
+			expectedNotes: []string{"invalid number of rows -1 in the row group"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ReadParquet(bytes.NewReader(tt.data), int64(len(tt.data)), 10)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRows, file.Table.Rows)
+			assert.Equal(t, tt.expectedNotes, file.Notes)
+		})
+	}
+}
+
+// TestReadParquet_DeepSchema tests that a schema nested too deeply is rejected instead of exhausting the stack
+func TestReadParquet_DeepSchema(t *testing.T) {
+	schema := []func(w *thriftWriter){
+		func(w *thriftWriter) { schemaElement(w, -1, -1, "schema", 1, nil) },
+	}
+	for i := 0; i < 100000; i++ {
+		schema = append(schema, func(w *thriftWriter) { schemaElement(w, -1, 0, "group", 1, nil) })
+	}
+	data := buildParquet(0, schema, []parquetTestColumn{{physicalType: parquetInt64, path: []string{"id"}}})
+
+	_, err := ReadParquet(bytes.NewReader(data), int64(len(data)), 10)
+	assert.ErrorContains(t, err, "nested too deeply")
+}
+
+// FuzzReadParquet tests that no input crashes the decoder. The seed corpus in testdata/fuzz/FuzzReadParquet
+// covers each codec, DATA_PAGE_V2, dictionaries and the INT96, DECIMAL and BOOLEAN values.
+func FuzzReadParquet(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ReadParquet(bytes.NewReader(data), int64(len(data)), 10)
+	})
+}
+
+// TestDecodeHybrid tests decoding the RLE/bit-packing hybrid encoding
+func TestDecodeHybrid(t *testing.T) {
+	// A run of four 5s followed by a bit-packed group of 0..7 with a bit width of 3
+	data := []byte{4 << 1, 5, 1<<1 | 1, 0b10001000, 0b11000110, 0b11111010}
+	values, err := decodeHybrid(data, 3, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{5, 5, 5, 5, 0, 1, 2, 3, 4, 5, 6, 7}, values)
+
+	_, err = decodeHybrid(data[:3], 3, 12)
+	assert.Error(t, err)
+
+	_, err = decodeHybrid(data, 3, -1)
+	assert.Error(t, err)
+
+	// Bit width 0 packs any number of zeros in no bytes
+	values, err = decodeHybrid(binary.AppendUvarint(nil, math.MaxUint64), 0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{0, 0, 0, 0}, values)
+}
+
+// TestFormatDecimal tests formatting unscaled decimals
+func TestFormatDecimal(t *testing.T) {
+	assert.Equal(t, "12.34", formatDecimal(big.NewInt(1234), 0, 2))
+	assert.Equal(t, "-0.05", formatDecimal(big.NewInt(-5), 0, 2))
+	assert.Equal(t, "100", formatDecimal(big.NewInt(100), 0, 0))
+	// 0xff38 is -200 in two's complement
+	assert.Equal(t, "-2.00", formatDecimal(new(big.Int).SetBytes([]byte{0xff, 0x38}), 2, 2))
+	// A huge scale from the metadata is not expanded into zeros
+	assert.Equal(t, "5e-2147483647", formatDecimal(big.NewInt(5), 0, math.MaxInt32))
+}
@@ -0,0 +1,133 @@
+// Package preview decides how objects are previewed in the browser and renders their content.
+package preview
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/korosuke613/polybuckets/internal/decompress"
+)
+
+// Kind is the way an object is previewed.
+type Kind string
+
+const (
+	// KindText is a preview of the text with syntax highlighting.
+	KindText Kind = "text"
+	// KindMarkdown is rendered to sanitized HTML.
+	KindMarkdown Kind = "markdown"
+	// KindTable is a table of the first rows of CSV, TSV or JSON Lines, which may be gzip-compressed.
+	KindTable Kind = "table"
+	// KindParquet is the schema, the row group statistics and the first rows of a Parquet file.
+	KindParquet Kind = "parquet"
+	// KindAvro is the schema and the first records of an Avro object container file.
+	KindAvro Kind = "avro"
+	// KindImage is shown with an img element loading the object from the download route.
+	KindImage Kind = "image"
+	// KindAudio is played with an audio element, which seeks with Range requests to the download route.
+	KindAudio Kind = "audio"
+	// KindVideo is played with a video element, which seeks with Range requests to the download route.
+	KindVideo Kind = "video"
+	// KindNone means the object cannot be previewed and is only offered for download.
+	KindNone Kind = "none"
+)
+
+// mediaExtensions are the extensions of objects that are previewed by the browser itself.
+var mediaExtensions = map[string]Kind{
+	".png": KindImage, ".jpg": KindImage, ".jpeg": KindImage, ".gif": KindImage, ".webp": KindImage,
+	".svg": KindImage, ".bmp": KindImage, ".ico": KindImage, ".avif": KindImage,
+	".mp3": KindAudio, ".wav": KindAudio, ".ogg": KindAudio, ".oga": KindAudio, ".m4a": KindAudio,
+	".aac": KindAudio, ".flac": KindAudio, ".opus": KindAudio,
+	".mp4": KindVideo, ".m4v": KindVideo, ".webm": KindVideo, ".ogv": KindVideo, ".mov": KindVideo,
+}
+
+// textExtensions are the extensions of objects that are previewed as text regardless of their content.
+var textExtensions = map[string]bool{
+	".txt": true, ".log": true,
+	".json": true, ".yaml": true, ".yml": true, ".toml": true,
+	".ini": true, ".conf": true, ".cfg": true, ".env": true, ".properties": true,
+	".xml": true, ".html": true, ".htm": true, ".css": true,
+	".js": true, ".mjs": true, ".ts": true, ".tsx": true, ".jsx": true,
+	".go": true, ".py": true, ".rb": true, ".rs": true, ".java": true, ".kt": true, ".scala": true,
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true, ".cs": true, ".swift": true,
+	".php": true, ".pl": true, ".lua": true, ".r": true, ".sql": true, ".graphql": true, ".proto": true,
+	".sh": true, ".bash": true, ".zsh": true, ".ps1": true, ".bat": true,
+	".tf": true, ".hcl": true, ".dockerfile": true, ".mk": true, ".diff": true, ".patch": true,
+}
+
+// KindByExtension returns the preview kind of an object from the extension of its key,
+// or KindNone if the extension is unknown.
+func KindByExtension(key string) Kind {
+	// Compressed objects are previewed by their decompressed content
+	if inner := decompress.TrimExtension(key); inner != key {
+		if kind := KindByExtension(inner); kind.ReadsHead() {
+			return kind
+		}
+		return KindNone
+	}
+
+	ext := strings.ToLower(path.Ext(key))
+	if kind, ok := mediaExtensions[ext]; ok {
+		return kind
+	}
+	if tableExtensions[tableExtension(key)] {
+		return KindTable
+	}
+	if ext == ".parquet" {
+		return KindParquet
+	}
+	if ext == ".avro" {
+		return KindAvro
+	}
+	if ext == ".md" || ext == ".markdown" {
+		return KindMarkdown
+	}
+	if textExtensions[ext] {
+		return KindText
+	}
+	return KindNone
+}
+
+// IsMedia reports whether the kind is previewed by the browser loading the object itself.
+func (k Kind) IsMedia() bool {
+	return k == KindImage || k == KindAudio || k == KindVideo
+}
+
+// ReadsHead reports whether the preview of the kind is rendered from the beginning of the object.
+// Media is loaded by the browser and Parquet files are read from their footer instead.
+func (k Kind) ReadsHead() bool {
+	return !k.IsMedia() && k != KindParquet
+}
+
+// DetectKind returns the preview kind of an object from its key and the beginning of its content.
+// Objects with an unknown extension are previewed as text if their content looks like text.
+func DetectKind(key string, head []byte) Kind {
+	if kind := KindByExtension(key); kind != KindNone {
+		return kind
+	}
+	if IsText(head) {
+		return KindText
+	}
+	return KindNone
+}
+
+// IsText reports whether data looks like UTF-8 text. data may end in the middle of a character.
+func IsText(data []byte) bool {
+	data = TrimIncompleteRune(data)
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// TrimIncompleteRune removes a UTF-8 character cut off at the end of data, e.g. by a Range request.
+func TrimIncompleteRune(data []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			return data
+		}
+	}
+	return data
+}
@@ -0,0 +1,60 @@
+package preview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDetectKind tests detecting the preview kind of objects
+func TestDetectKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		head     []byte
+		expected Kind
+	}{
+		{name: "拡張子がテキスト", key: "logs/app.LOG", head: []byte{0, 1, 2}, expected: KindText},
+		{name: "拡張子が不明でも内容がテキスト", key: "Makefile", head: []byte("all:\n\tgo build\n"), expected: KindText},
+		{name: "拡張子が不明で内容がバイナリ", key: "bin/app", head: []byte{0x7f, 'E', 'L', 'F', 0}, expected: KindNone},
+		{name: "不正なUTF-8", key: "data.bin", head: []byte{0xff, 0xfe, 'a'}, expected: KindNone},
+		{name: "画像", key: "screenshots/a.PNG", head: []byte{0x89, 'P', 'N', 'G'}, expected: KindImage},
+		{name: "表", key: "export/2025.CSV.gz", expected: KindTable},
+		{name: "Markdown", key: "README.md", head: []byte("# Title"), expected: KindMarkdown},
+		{name: "Parquet", key: "data/part-0.parquet", head: []byte("PAR1"), expected: KindParquet},
+		{name: "Avro", key: "events.avro", head: []byte("Obj\x01"), expected: KindAvro},
+		{name: "圧縮されたログ", key: "logs/app.log.zst", head: []byte("2025-01-01"), expected: KindText},
+		{name: "圧縮された不明な拡張子", key: "dump.gz", head: []byte("hello"), expected: KindText},
+		{name: "圧縮された画像", key: "a.png.gz", head: []byte{0x89, 'P', 'N', 'G'}, expected: KindNone},
+		{name: "音声", key: "a.mp3", expected: KindAudio},
+		{name: "動画", key: "a.webm", expected: KindVideo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectKind(tt.key, tt.head))
+		})
+	}
+}
+
+// TestTrimIncompleteRune tests removing a UTF-8 character cut off at the end
+func TestTrimIncompleteRune(t *testing.T) {
+	full := []byte("abcあ")
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{name: "完全な文字列", input: full, expected: full},
+		{name: "マルチバイト文字の途中で切れている", input: full[:len(full)-1], expected: []byte("abc")},
+		{name: "マルチバイト文字の先頭バイトのみ", input: full[:len(full)-2], expected: []byte("abc")},
+		{name: "空", input: []byte{}, expected: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TrimIncompleteRune(tt.input))
+		})
+	}
+}
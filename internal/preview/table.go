@@ -0,0 +1,246 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/korosuke613/polybuckets/internal/decompress"
+)
+
+// DefaultTableRows is the default maximum number of rows of a table preview.
+const DefaultTableRows = 1000
+
+// maxDecompressedBytes limits the decompressed size of a table preview to protect the server from compression bombs.
+const maxDecompressedBytes = 64 << 20
+
+// delimiters are the candidates of the CSV delimiter detection.
+var delimiters = []rune{',', '\t', ';', '|'}
+
+// tableExtensions are the extensions of objects previewed as a table.
+var tableExtensions = map[string]bool{".csv": true, ".tsv": true, ".jsonl": true, ".ndjson": true}
+
+// Table is the beginning of a tabular object.
+type Table struct {
+	Header []string
+	Rows   [][]string
+	// Truncated reports whether rows after Rows are omitted.
+	Truncated bool
+}
+
+// ParseTable parses CSV, TSV or JSON Lines from the beginning of an object, up to maxRows rows.
+// The format is detected from the key, compression is detected from the content and decompressed,
+// and the delimiter of CSV is detected from the content.
+// truncated reports whether data is only the beginning of the object; the last incomplete line is then dropped.
+func ParseTable(key string, data []byte, truncated bool, maxRows int) (*Table, error) {
+	if format := decompress.Detect(data); format != "" {
+		var err error
+		data, truncated, err = decompress.Prefix(format, data, truncated, maxDecompressedBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %q: %w", key, err)
+		}
+	}
+	if truncated {
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+
+	var table *Table
+	var err error
+	switch tableExtension(key) {
+	case ".jsonl", ".ndjson":
+		table, err = parseJSONLines(data, maxRows)
+	case ".tsv":
+		table, err = parseCSV(data, '\t', maxRows)
+	default:
+		table, err = parseCSV(data, detectDelimiter(data), maxRows)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", key, err)
+	}
+	table.Truncated = table.Truncated || truncated
+	table.normalize()
+	return table, nil
+}
+
+// normalize makes all rows and the header the same length so that the columns are aligned.
+func (t *Table) normalize() {
+	columns := len(t.Header)
+	for _, row := range t.Rows {
+		columns = max(columns, len(row))
+	}
+	for len(t.Header) < columns {
+		t.Header = append(t.Header, "")
+	}
+	for i, row := range t.Rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		t.Rows[i] = row
+	}
+}
+
+// tableExtension returns the extension of the key ignoring the extension of its compression, e.g. ".gz".
+func tableExtension(key string) string {
+	return strings.ToLower(path.Ext(decompress.TrimExtension(key)))
+}
+
+// detectDelimiter returns the candidate that appears the same number of times in the first lines, and most often.
+// Quotes are not taken into account, which is good enough for detection.
+func detectDelimiter(data []byte) rune {
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	lines = lines[:min(len(lines), 10)]
+
+	best, bestCount := delimiters[0], 0
+	for _, delimiter := range delimiters {
+		count := strings.Count(lines[0], string(delimiter))
+		for _, line := range lines[1:] {
+			if strings.Count(line, string(delimiter)) != count {
+				count = 0
+				break
+			}
+		}
+		if count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// parseCSV parses delimiter-separated values whose first record is the header.
+// A record that cannot be parsed ends the table, unless it is the first one.
+func parseCSV(data []byte, delimiter rune, maxRows int) (*Table, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return &Table{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Header: header}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			table.Truncated = true
+			break
+		}
+		if len(table.Rows) == maxRows {
+			table.Truncated = true
+			break
+		}
+		table.Rows = append(table.Rows, record)
+	}
+	return table, nil
+}
+
+// parseJSONLines parses JSON Lines whose columns are the keys of the objects in order of appearance.
+// Values that are not strings are shown as JSON, and lines that are not objects are shown in the "value" column.
+func parseJSONLines(data []byte, maxRows int) (*Table, error) {
+	table := &Table{}
+	columns := make(map[string]int)
+	column := func(name string) int {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+		columns[name] = len(table.Header)
+		table.Header = append(table.Header, name)
+		return columns[name]
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if len(table.Rows) == maxRows {
+			table.Truncated = true
+			break
+		}
+
+		fields, err := jsonObjectFields(line)
+		if err != nil {
+			if len(table.Rows) == 0 {
+				return nil, err
+			}
+			table.Truncated = true
+			break
+		}
+		row := make([]string, len(table.Header))
+		for _, field := range fields {
+			i := column(field.name)
+			for len(row) <= i {
+				row = append(row, "")
+			}
+			row[i] = field.value
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// jsonField is a member of a JSON object in a JSON Lines row.
+type jsonField struct {
+	name  string
+	value string
+}
+
+// jsonObjectFields returns the members of the JSON object in the line in order.
+// A line with another JSON value is returned as the "value" member.
+func jsonObjectFields(line []byte) ([]jsonField, error) {
+	if line[0] != '{' {
+		var value json.RawMessage
+		if err := json.Unmarshal(line, &value); err != nil {
+			return nil, err
+		}
+		return []jsonField{{name: "value", value: jsonString(value)}}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	var fields []jsonField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{name: token.(string), value: jsonString(value)})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// jsonString returns a JSON string as its content, and other JSON values as compact JSON.
+func jsonString(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, value) == nil {
+		return buf.String()
+	}
+	return string(value)
+}
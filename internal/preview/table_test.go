@@ -0,0 +1,105 @@
+package preview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseTable tests parsing tabular objects
+func TestParseTable(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("name,age\nalice,30\nbob,25\n"))
+	_ = gz.Close()
+
+	tests := []struct {
+		name      string
+		key       string
+		data      []byte
+		truncated bool
+		maxRows   int
+		expected  *Table
+	}{
+		{
+			name:     "CSV",
+			key:      "a.csv",
+			data:     []byte("name,age\nalice,30\n\"bob, jr.\",25\n"),
+			maxRows:  10,
+			expected: &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}, {"bob, jr.", "25"}}},
+		},
+		{
+			name:     "セミコロン区切りを検出",
+			key:      "a.csv",
+			data:     []byte("name;note\nalice;a,b\nbob;c,d\n"),
+			maxRows:  10,
+			expected: &Table{Header: []string{"name", "note"}, Rows: [][]string{{"alice", "a,b"}, {"bob", "c,d"}}},
+		},
+		{
+			name:     "TSV",
+			key:      "a.tsv",
+			data:     []byte("name\tage\nalice\t30\n"),
+			maxRows:  10,
+			expected: &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}}},
+		},
+		{
+			name:      "途中で切れた最終行は除外",
+			key:       "a.csv",
+			data:      []byte("name,age\nalice,30\nbo"),
+			truncated: true,
+			maxRows:   10,
+			expected:  &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}}, Truncated: true},
+		},
+		{
+			name:     "最大行数で打ち切り",
+			key:      "a.csv",
+			data:     []byte("name,age\nalice,30\nbob,25\n"),
+			maxRows:  1,
+			expected: &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}}, Truncated: true},
+		},
+		{
+			name:     "gzipを展開",
+			key:      "a.csv.gz",
+			data:     compressed.Bytes(),
+			maxRows:  10,
+			expected: &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}, {"bob", "25"}}},
+		},
+		{
+			name:      "途中で切れたgzipを展開",
+			key:       "a.csv.gz",
+			data:      compressed.Bytes()[:compressed.Len()-8],
+			truncated: true,
+			maxRows:   10,
+			expected:  &Table{Header: []string{"name", "age"}, Rows: [][]string{{"alice", "30"}, {"bob", "25"}}, Truncated: true},
+		},
+		{
+			name:    "JSON Lines",
+			key:     "a.jsonl",
+			data:    []byte(`{"name":"alice","tags":["a"]}` + "\n" + `{"age":25,"name":"bob"}` + "\n"),
+			maxRows: 10,
+			expected: &Table{
+				Header: []string{"name", "tags", "age"},
+				Rows:   [][]string{{"alice", `["a"]`, ""}, {"bob", "", "25"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseTable(tt.key, tt.data, tt.truncated, tt.maxRows)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, table)
+		})
+	}
+}
+
+// TestParseTable_Error tests that unparsable objects are reported
+func TestParseTable_Error(t *testing.T) {
+	_, err := ParseTable("a.jsonl", []byte("not json\n"), false, 10)
+	assert.ErrorContains(t, err, `failed to parse "a.jsonl"`)
+
+	_, err = ParseTable("a.csv.gz", []byte{0x1f, 0x8b, 0}, false, 10)
+	assert.ErrorContains(t, err, `failed to decompress "a.csv.gz"`)
+}
@@ -0,0 +1,259 @@
+package preview
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Thrift compact protocol types.
+const (
+	thriftStop   = 0
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftMaxDepth limits the nesting of structs and containers to protect the server from malicious metadata.
+const thriftMaxDepth = 64
+
+// errThriftTruncated is returned when the Thrift data ends in the middle of a value.
+var errThriftTruncated = errors.New("thrift: unexpected end of data")
+
+// thriftFields is a decoded Thrift struct keyed by field ID.
+// Values are int64 for integers, bool, float64, []byte for binary, []interface{} for lists and sets,
+// [][2]interface{} for maps and thriftFields for structs.
+type thriftFields map[int16]interface{}
+
+// int returns the integer field, or 0 if it is missing.
+func (f thriftFields) int(id int16) int64 {
+	v, _ := f[id].(int64)
+	return v
+}
+
+// has reports whether the field is set.
+func (f thriftFields) has(id int16) bool {
+	_, ok := f[id]
+	return ok
+}
+
+// bytes returns the binary field, or nil if it is missing.
+func (f thriftFields) bytes(id int16) []byte {
+	v, _ := f[id].([]byte)
+	return v
+}
+
+// string returns the binary field as a string.
+func (f thriftFields) string(id int16) string {
+	return string(f.bytes(id))
+}
+
+// bool returns the boolean field, or def if it is missing.
+func (f thriftFields) bool(id int16, def bool) bool {
+	if v, ok := f[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// strct returns the struct field, or an empty struct if it is missing.
+func (f thriftFields) strct(id int16) thriftFields {
+	if v, ok := f[id].(thriftFields); ok {
+		return v
+	}
+	return thriftFields{}
+}
+
+// list returns the list field, or nil if it is missing.
+func (f thriftFields) list(id int16) []interface{} {
+	v, _ := f[id].([]interface{})
+	return v
+}
+
+// thriftReader decodes the Thrift compact protocol, which Parquet uses for its metadata.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+// readStruct decodes a struct starting at the current position.
+func (r *thriftReader) readStruct(depth int) (thriftFields, error) {
+	if depth > thriftMaxDepth {
+		return nil, errors.New("thrift: nested too deeply")
+	}
+	fields := thriftFields{}
+	var lastID int16
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		fieldType := header & 0x0f
+		if fieldType == thriftStop {
+			return fields, nil
+		}
+		if delta := header >> 4; delta != 0 {
+			lastID += int16(delta)
+		} else {
+			id, err := r.readVarint()
+			if err != nil {
+				return nil, err
+			}
+			lastID = int16(zigzag(id))
+		}
+
+		// Booleans of fields are encoded in the type
+		switch fieldType {
+		case thriftTrue:
+			fields[lastID] = true
+			continue
+		case thriftFalse:
+			fields[lastID] = false
+			continue
+		}
+		value, err := r.readValue(fieldType, depth)
+		if err != nil {
+			return nil, err
+		}
+		fields[lastID] = value
+	}
+}
+
+// readValue decodes a value of the type.
+func (r *thriftReader) readValue(valueType byte, depth int) (interface{}, error) {
+	if depth > thriftMaxDepth {
+		return nil, errors.New("thrift: nested too deeply")
+	}
+	switch valueType {
+	case thriftTrue, thriftFalse:
+		// Booleans in lists and maps are encoded as a byte
+		b, err := r.readByte()
+		return b == thriftTrue, err
+	case thriftByte:
+		b, err := r.readByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		v, err := r.readVarint()
+		return zigzag(v), err
+	case thriftDouble:
+		if r.pos+8 > len(r.data) {
+			return nil, errThriftTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftBinary:
+		return r.readBinary()
+	case thriftList, thriftSet:
+		return r.readList(depth)
+	case thriftMap:
+		return r.readMap(depth)
+	case thriftStruct:
+		return r.readStruct(depth + 1)
+	}
+	return nil, fmt.Errorf("thrift: unknown type %d", valueType)
+}
+
+// readList decodes a list or a set.
+func (r *thriftReader) readList(depth int) ([]interface{}, error) {
+	header, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(header >> 4)
+	if size == 15 {
+		if size, err = r.readVarint(); err != nil {
+			return nil, err
+		}
+	}
+	// Every element takes at least a byte
+	if size > uint64(len(r.data)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	list := make([]interface{}, 0, size)
+	for i := uint64(0); i < size; i++ {
+		value, err := r.readValue(header&0x0f, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// readMap decodes a map as key-value pairs.
+func (r *thriftReader) readMap(depth int) ([][2]interface{}, error) {
+	size, err := r.readVarint()
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	if size > uint64(len(r.data)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	types, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([][2]interface{}, 0, size)
+	for i := uint64(0); i < size; i++ {
+		key, err := r.readValue(types>>4, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.readValue(types&0x0f, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]interface{}{key, value})
+	}
+	return pairs, nil
+}
+
+// readBinary decodes a length-prefixed binary.
+func (r *thriftReader) readBinary() ([]byte, error) {
+	length, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	b := r.data[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+	return b, nil
+}
+
+// readByte reads a byte.
+func (r *thriftReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errThriftTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// readVarint reads an unsigned LEB128 varint.
+func (r *thriftReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errThriftTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+// zigzag decodes a zigzag-encoded integer.
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
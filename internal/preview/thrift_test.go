@@ -0,0 +1,126 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// thriftWriter encodes the Thrift compact protocol to build Parquet metadata in tests.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastIDs []int16
+}
+
+// begin starts a struct.
+func (w *thriftWriter) begin() {
+	w.lastIDs = append(w.lastIDs, 0)
+}
+
+// end ends a struct.
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(thriftStop)
+	w.lastIDs = w.lastIDs[:len(w.lastIDs)-1]
+}
+
+// field writes a field header.
+func (w *thriftWriter) field(id int16, fieldType byte) {
+	last := w.lastIDs[len(w.lastIDs)-1]
+	if id > last && id-last <= 15 {
+		w.buf.WriteByte(byte(id-last)<<4 | fieldType)
+	} else {
+		w.buf.WriteByte(fieldType)
+		w.varint(int64(id))
+	}
+	w.lastIDs[len(w.lastIDs)-1] = id
+}
+
+// varint writes a zigzag varint.
+func (w *thriftWriter) varint(v int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(v<<1^(v>>63))))
+}
+
+// binary writes a length-prefixed binary.
+func (w *thriftWriter) binary(b []byte) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	w.buf.Write(b)
+}
+
+// i32 writes an integer field. The compact protocol encodes i32 and i64 in the same way.
+func (w *thriftWriter) i32(id int16, v int64) {
+	w.field(id, thriftI32)
+	w.varint(v)
+}
+
+// i64 writes an i64 field.
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+// bool writes a boolean field.
+func (w *thriftWriter) bool(id int16, v bool) {
+	if v {
+		w.field(id, thriftTrue)
+	} else {
+		w.field(id, thriftFalse)
+	}
+}
+
+// bytes writes a binary field.
+func (w *thriftWriter) bytes(id int16, b []byte) {
+	w.field(id, thriftBinary)
+	w.binary(b)
+}
+
+// strct writes a struct field whose fields are written by fn.
+func (w *thriftWriter) strct(id int16, fn func()) {
+	w.field(id, thriftStruct)
+	w.begin()
+	fn()
+	w.end()
+}
+
+// list writes a list field of n elements written by fn.
+func (w *thriftWriter) list(id int16, elementType byte, n int, fn func(i int)) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elementType)
+	} else {
+		w.buf.WriteByte(0xf0 | elementType)
+		w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+	}
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+}
+
+// TestThriftReader tests decoding the Thrift compact protocol
+func TestThriftReader(t *testing.T) {
+	w := &thriftWriter{}
+	w.begin()
+	w.i32(1, -3)
+	w.bytes(2, []byte("name"))
+	w.bool(3, true)
+	w.i64(100, 1<<40)
+	w.strct(101, func() { w.bool(1, false) })
+	w.list(102, thriftBinary, 2, func(i int) { w.binary([]byte{byte('a' + i)}) })
+	w.end()
+
+	fields, err := (&thriftReader{data: w.buf.Bytes()}).readStruct(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-3), fields.int(1))
+	assert.Equal(t, "name", fields.string(2))
+	assert.True(t, fields.bool(3, false))
+	assert.Equal(t, int64(1<<40), fields.int(100))
+	assert.False(t, fields.strct(101).bool(1, true))
+	assert.Equal(t, []interface{}{[]byte("a"), []byte("b")}, fields.list(102))
+
+	// Truncated data is reported instead of panicking
+	for i := 0; i < w.buf.Len(); i++ {
+		_, err := (&thriftReader{data: w.buf.Bytes()[:i]}).readStruct(0)
+		assert.ErrorIs(t, err, errThriftTruncated)
+	}
+}
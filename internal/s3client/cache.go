@@ -0,0 +1,208 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/korosuke613/polybuckets/internal/env"
+)
+
+// DefaultCacheJanitorInterval is the default interval at which expired cache entries are removed.
+const DefaultCacheJanitorInterval = time.Minute
+
+// ListObjectsCacheEntry contains the data and expiry time for a listObjects cache entry.
+// The entry is fresh until Expiry and may be served while being refreshed until StaleExpiry.
+// page is the 1-based page number, or 0 if it is not known, and prevToken is the continuation token of the previous page.
+type ListObjectsCacheEntry struct {
+	data        *s3.ListObjectsV2Output
+	page        int
+	prevToken   string
+	Expiry      time.Time
+	StaleExpiry time.Time
+}
+
+// expired reports whether the entry can no longer be served at the specified time.
+func (e ListObjectsCacheEntry) expired(now time.Time) bool {
+	return e.Expiry.Before(now) && e.StaleExpiry.Before(now)
+}
+
+// Approximate in-memory overheads used by ListObjectsCacheEntry.Size.
+const (
+	cacheEntryOverhead   = 256
+	commonPrefixOverhead = 32
+	objectOverhead       = 128
+)
+
+// Size returns the approximate number of bytes the entry occupies in memory.
+func (e ListObjectsCacheEntry) Size() int64 {
+	size := int64(cacheEntryOverhead)
+	if e.data == nil {
+		return size
+	}
+	size += int64(len(e.prevToken) + len(aws.ToString(e.data.ContinuationToken)) + len(aws.ToString(e.data.NextContinuationToken)))
+	for _, commonPrefix := range e.data.CommonPrefixes {
+		size += commonPrefixOverhead + int64(len(aws.ToString(commonPrefix.Prefix)))
+	}
+	for _, obj := range e.data.Contents {
+		size += objectOverhead + int64(len(aws.ToString(obj.Key))+len(aws.ToString(obj.ETag)))
+	}
+	return size
+}
+
+// ListObjectsCache is the storage backend of the listObjects cache.
+// Implementations must be safe for concurrent use.
+type ListObjectsCache interface {
+	// Get returns the entry for the specified key.
+	Get(key string) (ListObjectsCacheEntry, bool)
+	// Set stores the entry for the specified key.
+	Set(key string, entry ListObjectsCacheEntry)
+	// DeletePrefix removes all entries whose key starts with the specified prefix.
+	DeletePrefix(keyPrefix string)
+	// DeleteExpired removes all entries that have expired at the specified time.
+	DeleteExpired(now time.Time)
+	// Len returns the number of entries in the cache.
+	Len() int
+	// Range calls fn for each entry until fn returns false. fn must not call other methods of the cache.
+	Range(fn func(key string, entry ListObjectsCacheEntry) bool)
+}
+
+// Cache backend names accepted by NewListObjectsCache.
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendLRU    = "lru"
+	CacheBackendDisk   = "disk"
+)
+
+// NewListObjectsCache creates the listObjects cache backend selected by the configuration.
+func NewListObjectsCache(pbConfig *env.PBConfigType) (ListObjectsCache, error) {
+	switch pbConfig.CacheBackend {
+	case CacheBackendMemory:
+		return NewMemoryCache(), nil
+	case CacheBackendLRU, "":
+		return NewLRUCache(pbConfig.CacheMaxEntries, pbConfig.CacheMaxBytes), nil
+	case CacheBackendDisk:
+		return NewDiskCache(pbConfig.CacheDiskPath)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", pbConfig.CacheBackend)
+	}
+}
+
+// memoryCache is an unbounded in-memory listObjects cache that only shrinks on expiry.
+type memoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]ListObjectsCacheEntry
+}
+
+// NewMemoryCache creates an empty unbounded in-memory listObjects cache.
+func NewMemoryCache() ListObjectsCache {
+	return &memoryCache{
+		entries: make(map[string]ListObjectsCacheEntry),
+	}
+}
+
+// Get returns the entry for the specified key.
+func (c *memoryCache) Get(key string) (ListObjectsCacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, found := c.entries[key]
+	return entry, found
+}
+
+// Set stores the entry for the specified key.
+func (c *memoryCache) Set(key string, entry ListObjectsCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+}
+
+// DeletePrefix removes all entries whose key starts with the specified prefix.
+func (c *memoryCache) DeletePrefix(keyPrefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, keyPrefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// DeleteExpired removes all entries that have expired at the specified time.
+func (c *memoryCache) DeleteExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *memoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Range calls fn for each entry until fn returns false.
+func (c *memoryCache) Range(fn func(key string, entry ListObjectsCacheEntry) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key, entry := range c.entries {
+		if !fn(key, entry) {
+			return
+		}
+	}
+}
+
+// ListBucketsCacheEntry contains the data and expiry time for the listBuckets cache entry.
+type ListBucketsCacheEntry struct {
+	data   []BucketInfo
+	Expiry time.Time
+}
+
+// listBucketsCache holds the single listBuckets cache entry. The zero value is an empty cache.
+type listBucketsCache struct {
+	mu    sync.RWMutex
+	entry *ListBucketsCacheEntry
+}
+
+// Get returns the cache entry, or nil if there is none.
+func (c *listBucketsCache) Get() *ListBucketsCacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.entry
+}
+
+// Set stores the cache entry.
+func (c *listBucketsCache) Set(entry *ListBucketsCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry = entry
+}
+
+// StartCacheJanitor starts a single background goroutine that clears expired listObjects cache entries
+// at the specified interval until the context is cancelled.
+func (c *Client) StartCacheJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCacheJanitorInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.ClearOldListObjectsCache(ctx)
+			}
+		}
+	}()
+}
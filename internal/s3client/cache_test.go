@@ -0,0 +1,272 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/stretchr/testify/assert"
+)
+
+// cacheBackends returns a fresh instance of each ListObjectsCache implementation
+func cacheBackends(t *testing.T) map[string]ListObjectsCache {
+	disk, err := NewDiskCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { disk.(*diskCache).Close() })
+
+	return map[string]ListObjectsCache{
+		"memory": NewMemoryCache(),
+		"lru":    NewLRUCache(100, 1<<20),
+		"disk":   disk,
+	}
+}
+
+// TestListObjectsCache tests the basic operations of each ListObjectsCache implementation
+func TestListObjectsCache(t *testing.T) {
+	for name, cache := range cacheBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			cache.Set("bucket/a?token=", ListObjectsCacheEntry{Expiry: now.Add(time.Minute)})
+			cache.Set("bucket/a?token=token2", ListObjectsCacheEntry{Expiry: now.Add(-time.Minute)})
+			cache.Set("bucket/b?token=", ListObjectsCacheEntry{Expiry: now.Add(time.Minute)})
+
+			entry, found := cache.Get("bucket/a?token=")
+			assert.True(t, found)
+			assert.True(t, now.Add(time.Minute).Equal(entry.Expiry))
+
+			cache.DeleteExpired(now)
+			assert.Equal(t, 2, cache.Len())
+			_, found = cache.Get("bucket/a?token=token2")
+			assert.False(t, found)
+
+			cache.DeletePrefix("bucket/a?token=")
+			assert.Equal(t, 1, cache.Len())
+			_, found = cache.Get("bucket/b?token=")
+			assert.True(t, found)
+
+			var keys []string
+			cache.Range(func(key string, entry ListObjectsCacheEntry) bool {
+				keys = append(keys, key)
+				return true
+			})
+			assert.Equal(t, []string{"bucket/b?token="}, keys)
+		})
+	}
+}
+
+// TestListObjectsCache_Concurrent hammers each ListObjectsCache implementation from many goroutines.
+// Run with `go test -race` to detect data races.
+func TestListObjectsCache_Concurrent(t *testing.T) {
+	for name, cache := range cacheBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 32; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						key := fmt.Sprintf("bucket/%d?token=%d", i%4, j%8)
+						cache.Set(key, ListObjectsCacheEntry{Expiry: time.Now().Add(time.Duration(j%3-1) * time.Second)})
+						cache.Get(key)
+						cache.Len()
+						switch j % 50 {
+						case 0:
+							cache.DeletePrefix(fmt.Sprintf("bucket/%d?token=", i%4))
+						case 25:
+							cache.DeleteExpired(time.Now())
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestNewListObjectsCache tests that the backend is selected by the configuration
+func TestNewListObjectsCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     string
+		expected    ListObjectsCache
+		expectedErr string
+	}{
+		{name: "正常系: デフォルトはLRU", backend: "", expected: &lruCache{}},
+		{name: "正常系: LRU", backend: CacheBackendLRU, expected: &lruCache{}},
+		{name: "正常系: メモリ", backend: CacheBackendMemory, expected: &memoryCache{}},
+		{name: "異常系: 不明なバックエンド", backend: "unknown", expectedErr: "unknown cache backend \"unknown\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := NewListObjectsCache(&env.PBConfigType{CacheBackend: tt.backend})
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, tt.expected, cache)
+		})
+	}
+}
+
+// TestClient_ListObjects_Concurrent hammers ListObjects together with the cache clearing methods.
+// Run with `go test -race` to detect data races.
+func TestClient_ListObjects_Concurrent(t *testing.T) {
+	mock := &MockS3Client{
+		listObjectsOutput: &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)},
+	}
+	client := &Client{s3Client: mock, CacheDuration: time.Millisecond, listObjectsCache: NewMemoryCache()}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prefix := fmt.Sprintf("dir%d", i%4)
+			for j := 0; j < 100; j++ {
+				_, _, err := client.ListObjects(ctx, "test-bucket", prefix, "", "")
+				assert.NoError(t, err)
+				client.GetListObjectsCacheEntry(ctx, "test-bucket", prefix, "")
+				switch j % 10 {
+				case 0:
+					client.ClearListObjectsCache(ctx, "test-bucket", prefix)
+				case 5:
+					client.ClearOldListObjectsCache(ctx)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestClient_StartCacheJanitor tests that the janitor clears expired entries and stops with the context
+func TestClient_StartCacheJanitor(t *testing.T) {
+	client := &Client{listObjectsCache: NewMemoryCache()}
+	client.listObjectsCache.Set("bucket/a?token=", ListObjectsCacheEntry{Expiry: time.Now().Add(-time.Minute)})
+	client.listObjectsCache.Set("bucket/b?token=", ListObjectsCacheEntry{Expiry: time.Now().Add(time.Hour)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.StartCacheJanitor(ctx, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return client.listObjectsCache.Len() == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestClient_ListObjects_Coalesce tests that concurrent cache misses for the same page share one upstream call
+func TestClient_ListObjects_Coalesce(t *testing.T) {
+	mock := &MockS3Client{
+		listObjectsOutput: &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)},
+		listObjectsBlock:  make(chan struct{}),
+	}
+	client := &Client{s3Client: mock, CacheDuration: time.Minute, listObjectsCache: NewMemoryCache()}
+	ctx := context.Background()
+	const requests = 10
+
+	var wg sync.WaitGroup
+	list := func() {
+		defer wg.Done()
+		_, hitCache, err := client.ListObjects(ctx, "test-bucket", "dir", "", "")
+		assert.NoError(t, err)
+		assert.False(t, hitCache)
+	}
+
+	// The first request starts the upstream call and blocks in it
+	wg.Add(1)
+	go list()
+	assert.Eventually(t, func() bool {
+		return mock.listObjectsCalls.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// The other requests wait for the in-flight call
+	for i := 1; i < requests; i++ {
+		wg.Add(1)
+		go list()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(mock.listObjectsBlock)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), mock.listObjectsCalls.Load())
+	assert.Equal(t, Metrics{ListObjectsUpstreamCalls: 1, ListObjectsCoalesced: requests - 1, ListObjectsCacheMisses: requests}, client.Metrics())
+}
+
+// TestClient_ClearCache tests purging the listObjects cache by prefix subtree, by bucket and entirely
+func TestClient_ClearCache(t *testing.T) {
+	keys := []string{
+		"bucket/?token=",
+		"bucket/dir?token=",
+		"bucket/dir?token=token2",
+		"bucket/dir/sub?token=",
+		"bucket/dir2?token=",
+		"other/dir?token=",
+	}
+
+	tests := []struct {
+		name     string
+		clear    func(ctx context.Context, client *Client)
+		expected []CacheEntryInfo
+	}{
+		{
+			name: "正常系: プレフィックス配下を削除",
+			clear: func(ctx context.Context, client *Client) {
+				client.ClearListObjectsCacheTree(ctx, "bucket", "dir/")
+			},
+			expected: []CacheEntryInfo{{Key: "bucket/?token="}, {Key: "bucket/dir2?token="}, {Key: "other/dir?token="}},
+		},
+		{
+			name: "正常系: バケット全体を削除",
+			clear: func(ctx context.Context, client *Client) {
+				client.ClearListObjectsCacheTree(ctx, "bucket", "")
+			},
+			expected: []CacheEntryInfo{{Key: "other/dir?token="}},
+		},
+		{
+			name: "正常系: オブジェクトの変更で祖先のプレフィックスを削除",
+			clear: func(ctx context.Context, client *Client) {
+				client.InvalidateObject(ctx, "bucket", "dir/sub/file.txt")
+			},
+			expected: []CacheEntryInfo{{Key: "bucket/dir2?token="}, {Key: "other/dir?token="}},
+		},
+		{
+			name: "正常系: すべて削除",
+			clear: func(ctx context.Context, client *Client) {
+				client.ClearAllCache(ctx)
+			},
+			expected: []CacheEntryInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := &Client{listObjectsCache: NewMemoryCache()}
+			for _, key := range keys {
+				client.listObjectsCache.Set(key, ListObjectsCacheEntry{})
+			}
+			client.listBucketsCache.Set(&ListBucketsCacheEntry{})
+
+			tt.clear(ctx, client)
+
+			entries := client.ListObjectsCacheEntries(ctx)
+			for i := range entries {
+				assert.Equal(t, int64(cacheEntryOverhead), entries[i].Size)
+				entries[i].Size = 0
+			}
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
@@ -2,15 +2,20 @@ package s3client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/korosuke613/polybuckets/internal/env"
+	"golang.org/x/sync/singleflight"
 )
 
 // S3Client defines the operations required for interacting with S3.
@@ -18,13 +23,24 @@ type S3Client interface {
 	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
 }
 
 // Client wraps the S3 client and provides additional functionality.
 type Client struct {
-	s3Client              S3Client
-	CacheDuration         time.Duration
-	listObjectsCacheEntry map[string]ListObjectsCacheEntry
+	s3Client             S3Client
+	presigner            S3Presigner
+	CacheDuration        time.Duration
+	StaleDuration        time.Duration
+	BucketsCacheDuration time.Duration
+	PageSize             int32
+	listObjectsCache     ListObjectsCache
+	listBucketsCache     listBucketsCache
+	listObjectsGroup     singleflight.Group
+	revalidating         sync.Map
+	metrics              clientMetrics
 }
 
 // ClientOption defines a function type for configuring the Client.
@@ -46,20 +62,9 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 			// Suppress warnings about checksum validation skipped in log output
 			// e.g. SDK 2025/01/26 02:05:17 WARN Response has no supported checksum. Not validating response payload.
 			o.DisableLogOutputChecksumValidationSkipped = true
-
-			// Use the specified endpoint if set, and enforce path style
-			if pbConfig.AWSEndpoint != "" {
-				// if endpoint without http/https is specified, add https
-				endpoint := pbConfig.AWSEndpoint 
-				if !strings.HasPrefix(pbConfig.AWSEndpoint, "http") {
-					endpoint = "http://" + endpoint
-				}
-
-				o.BaseEndpoint = aws.String(endpoint)
-				o.UsePathStyle = true
-			}
-		}),
-		listObjectsCacheEntry: make(map[string]ListObjectsCacheEntry),
+		}, withEndpoint(pbConfig.AWSEndpoint)),
+		presigner:        newPresigner(cfg, pbConfig),
+		listObjectsCache: NewMemoryCache(),
 	}
 
 	// Apply options
@@ -72,6 +77,22 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 	return client, nil
 }
 
+// withEndpoint uses the specified endpoint if set, and enforces path style.
+func withEndpoint(endpoint string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		if endpoint == "" {
+			return
+		}
+		// if endpoint without http/https is specified, add http
+		if !strings.HasPrefix(endpoint, "http") {
+			endpoint = "http://" + endpoint
+		}
+
+		o.BaseEndpoint = aws.String(endpoint)
+		o.UsePathStyle = true
+	}
+}
+
 // WithCustomClient injects a custom S3 client.
 func WithCustomClient(cli S3Client) ClientOption {
 	return func(c *Client) error {
@@ -88,28 +109,94 @@ func WithConfig(cfg aws.Config) ClientOption {
 	}
 }
 
+// WithListObjectsCache replaces the listObjects cache backend.
+func WithListObjectsCache(cache ListObjectsCache) ClientOption {
+	return func(c *Client) error {
+		c.listObjectsCache = cache
+		return nil
+	}
+}
+
 // BucketInfo contains information about an S3 bucket.
 type BucketInfo struct {
 	Name         string
 	CreationDate time.Time
 }
 
-// ListObjectsCacheEntry contains the data and expiry time for a listObjects cache entry.
-type ListObjectsCacheEntry struct {
-	data   *s3.ListObjectsV2Output
-	Expiry time.Time
+// listObjectsCacheKeyPrefix returns the cache key prefix shared by all pages of the specified bucket and prefix.
+func listObjectsCacheKeyPrefix(bucket, prefix string) string {
+	return fmt.Sprintf("%s/%s?token=", bucket, prefix)
+}
+
+// listObjectsCacheKey returns the cache key for the page of the bucket and prefix starting at the continuation token.
+// The token of the first page is empty.
+func listObjectsCacheKey(bucket, prefix, token string) string {
+	return listObjectsCacheKeyPrefix(bucket, prefix) + token
 }
 
-// ClearListObjectsCache clears the listObjects cache for the specified bucket and prefix.
+// ClearListObjectsCache clears the listObjects cache for all pages of the specified bucket and prefix.
+// All pages are cleared together because continuation tokens of a stale page are not valid for a fresh listing.
 func (c *Client) ClearListObjectsCache(ctx context.Context, bucket, prefix string) {
-	cacheKey := fmt.Sprintf("%s/%s", bucket, prefix)
-	delete(c.listObjectsCacheEntry, cacheKey)
+	c.listObjectsCache.DeletePrefix(listObjectsCacheKeyPrefix(bucket, prefix))
 }
 
-// GetListObjectsCacheEntry retrieves the listObjects cache entry for the specified bucket and prefix.
-func (c *Client) GetListObjectsCacheEntry(ctx context.Context, bucket, prefix string) *ListObjectsCacheEntry {
-	cacheKey := fmt.Sprintf("%s/%s", bucket, prefix)
-	entry, found := c.listObjectsCacheEntry[cacheKey]
+// ClearListObjectsCacheTree clears the listObjects cache for the specified prefix and all prefixes below it.
+// An empty prefix clears the whole bucket.
+func (c *Client) ClearListObjectsCacheTree(ctx context.Context, bucket, prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		c.listObjectsCache.DeletePrefix(bucket + "/")
+		return
+	}
+	c.listObjectsCache.DeletePrefix(listObjectsCacheKeyPrefix(bucket, prefix))
+	c.listObjectsCache.DeletePrefix(fmt.Sprintf("%s/%s/", bucket, prefix))
+}
+
+// InvalidateObject clears the listObjects cache affected by a change of the specified object.
+// The listings of all ancestor prefixes are cleared since creating or removing an object may add or remove folders.
+func (c *Client) InvalidateObject(ctx context.Context, bucket, key string) {
+	parts := strings.Split(strings.TrimSuffix(key, "/"), "/")
+	for i := range parts {
+		c.ClearListObjectsCache(ctx, bucket, strings.Join(parts[:i], "/"))
+	}
+}
+
+// ClearAllCache clears the listObjects and listBuckets caches entirely.
+func (c *Client) ClearAllCache(ctx context.Context) {
+	c.listObjectsCache.DeletePrefix("")
+	c.listBucketsCache.Set(nil)
+}
+
+// CacheEntryInfo describes a listObjects cache entry.
+type CacheEntryInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	Expiry      time.Time `json:"expiry"`
+	StaleExpiry time.Time `json:"stale_expiry"`
+}
+
+// ListObjectsCacheEntries returns the listObjects cache entries sorted by key.
+func (c *Client) ListObjectsCacheEntries(ctx context.Context) []CacheEntryInfo {
+	entries := []CacheEntryInfo{}
+	c.listObjectsCache.Range(func(key string, entry ListObjectsCacheEntry) bool {
+		entries = append(entries, CacheEntryInfo{
+			Key:         key,
+			Size:        entry.Size(),
+			Expiry:      entry.Expiry,
+			StaleExpiry: entry.StaleExpiry,
+		})
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// GetListObjectsCacheEntry retrieves the listObjects cache entry for the specified bucket, prefix and continuation token.
+func (c *Client) GetListObjectsCacheEntry(ctx context.Context, bucket, prefix, token string) *ListObjectsCacheEntry {
+	cacheKey := listObjectsCacheKey(bucket, prefix, token)
+	entry, found := c.listObjectsCache.Get(cacheKey)
 	if !found {
 		return nil
 	}
@@ -118,29 +205,50 @@ func (c *Client) GetListObjectsCacheEntry(ctx context.Context, bucket, prefix st
 
 // ClearOldListObjectsCache clears the listObjects cache for entries that have expired.
 func (c *Client) ClearOldListObjectsCache(ctx context.Context) {
-	now := time.Now()
-	for key, entry := range c.listObjectsCacheEntry {
-		if entry.Expiry.Before(now) {
-			delete(c.listObjectsCacheEntry, key)
-		}
-	}
+	c.listObjectsCache.DeleteExpired(time.Now())
+}
+
+// ClearListBucketsCache clears the listBuckets cache.
+func (c *Client) ClearListBucketsCache(ctx context.Context) {
+	c.listBucketsCache.Set(nil)
+}
+
+// GetListBucketsCacheEntry retrieves the listBuckets cache entry.
+func (c *Client) GetListBucketsCacheEntry(ctx context.Context) *ListBucketsCacheEntry {
+	return c.listBucketsCache.Get()
 }
 
 // ListBuckets lists all S3 buckets.
-func (c *Client) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+func (c *Client) ListBuckets(ctx context.Context) (buckets []BucketInfo, hitCache bool, err error) {
+	now := time.Now()
+
+	// if the cache exists and is within the expiration date, return the cache
+	if entry := c.listBucketsCache.Get(); entry != nil && entry.Expiry.After(now) {
+		c.metrics.listBucketsCacheHits.Add(1)
+		return entry.data, true, nil
+	}
+	c.metrics.listBucketsCacheMisses.Add(1)
+
 	result, err := c.s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
 	if err != nil {
-		return nil, fmt.Errorf("ListBuckets operation failed: %w", err)
+		return nil, false, fmt.Errorf("ListBuckets operation failed: %w", err)
 	}
 
-	buckets := make([]BucketInfo, len(result.Buckets))
+	buckets = make([]BucketInfo, len(result.Buckets))
 	for i, b := range result.Buckets {
 		buckets[i] = BucketInfo{
 			Name:         *b.Name,
 			CreationDate: *b.CreationDate,
 		}
 	}
-	return buckets, nil
+
+	// Save to cache
+	c.listBucketsCache.Set(&ListBucketsCacheEntry{
+		data:   buckets,
+		Expiry: now.Add(c.BucketsCacheDuration),
+	})
+
+	return buckets, false, nil
 }
 
 // ObjectInfo contains information about an S3 object.
@@ -150,41 +258,210 @@ type ObjectInfo struct {
 	IsDirectory  bool
 	Size         string
 	LastModified time.Time
+	// ETag is the ETag of the object in the listing, which is empty for folders.
+	ETag string
 }
 
-// ListObjects lists objects in the specified S3 bucket and prefix.
-func (c *Client) ListObjects(ctx context.Context, bucket, prefix string) (objectInfo []ObjectInfo, hitCache bool, err error) {
-	cacheKey := fmt.Sprintf("%s/%s", bucket, prefix)
-	now := time.Now()
+// ObjectsPage contains a single page of objects and the continuation tokens of the neighbouring pages.
+// Page is the 1-based page number, which is 0 if the page was not reached from the previous one.
+// PrevToken is only valid if HasPrev is true and is empty for the first page.
+// NextToken is empty when there is no next page.
+// Refreshing is true when a stale cached page is served while it is being refreshed in the background.
+type ObjectsPage struct {
+	Objects    []ObjectInfo
+	Page       int
+	Token      string
+	HasPrev    bool
+	PrevToken  string
+	NextToken  string
+	Refreshing bool
+}
+
+// ListObjects lists the page of objects in the specified S3 bucket and prefix starting at the continuation token,
+// which is empty for the first page.
+// prevToken is the token of the page the link to this page was on. It is only trusted if the cached previous page
+// continues with token, so that the page number and the link to the previous page are known.
+func (c *Client) ListObjects(ctx context.Context, bucket, prefix, token, prevToken string) (objectsPage *ObjectsPage, hitCache bool, err error) {
+	entry, hitCache, refreshing, err := c.listObjectsPage(ctx, bucket, prefix, token, prevToken)
+	if err != nil {
+		return nil, false, err
+	}
+	if hitCache {
+		c.metrics.listObjectsCacheHits.Add(1)
+	} else {
+		c.metrics.listObjectsCacheMisses.Add(1)
+	}
 
 	// Add a trailing slash to the prefix if it doesn't already have one
 	if prefix != "" && !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
 
-	// if the cache exists and is within the expiration date, return the cache
-	if entry, found := c.listObjectsCacheEntry[cacheKey]; found && entry.Expiry.After(now) {
-		return convertToObjectInfo(entry.data, prefix), true, nil
+	objectsPage = &ObjectsPage{
+		Objects:    convertToObjectInfo(entry.data, prefix),
+		Page:       entry.page,
+		Token:      token,
+		HasPrev:    entry.page > 1,
+		PrevToken:  entry.prevToken,
+		Refreshing: refreshing,
+	}
+	if aws.ToBool(entry.data.IsTruncated) {
+		objectsPage.NextToken = aws.ToString(entry.data.NextContinuationToken)
+	}
+	return objectsPage, hitCache, nil
+}
+
+// listObjectsPage returns the cache entry of the page starting at the continuation token, fetching it on a cache miss.
+// A cache entry past its expiry but within its stale expiry is returned as is while it is refreshed in the background.
+func (c *Client) listObjectsPage(ctx context.Context, bucket, prefix, token, prevToken string) (entry ListObjectsCacheEntry, hitCache bool, refreshing bool, err error) {
+	cacheKey := listObjectsCacheKey(bucket, prefix, token)
+	now := time.Now()
+
+	if entry, found := c.listObjectsCache.Get(cacheKey); found {
+		// if the cache exists and is within the expiration date, return the cache
+		if entry.Expiry.After(now) {
+			return entry, true, false, nil
+		}
+		// if the cache is stale but within the stale expiration date, return it and refresh it in the background
+		if entry.StaleExpiry.After(now) {
+			c.revalidateListObjectsPage(context.WithoutCancel(ctx), bucket, prefix, token, entry.page, entry.prevToken)
+			return entry, true, true, nil
+		}
+	}
+
+	// The first page is page 1; another page is numbered after the cached previous page only if it continues with token
+	page := 0
+	if token == "" {
+		page = 1
+	} else if prev, found := c.listObjectsCache.Get(listObjectsCacheKey(bucket, prefix, prevToken)); found &&
+		prev.page > 0 && prev.data != nil && aws.ToString(prev.data.NextContinuationToken) == token {
+		page = prev.page + 1
+	}
+
+	// Share a single upstream call between concurrent cache misses for the same page
+	executed := false
+	v, err, _ := c.listObjectsGroup.Do(cacheKey, func() (interface{}, error) {
+		executed = true
+		// Detach from the caller's cancellation since the result is shared with other requests
+		return c.fetchListObjectsPage(context.WithoutCancel(ctx), bucket, prefix, token, page, prevToken)
+	})
+	if !executed {
+		c.metrics.listObjectsCoalesced.Add(1)
+	}
+	if err != nil {
+		return ListObjectsCacheEntry{}, false, false, err
+	}
+	return v.(ListObjectsCacheEntry), false, false, nil
+}
+
+// revalidateListObjectsPage refreshes the cache of the page starting at the continuation token in the background.
+// At most one refresh per page runs at a time.
+func (c *Client) revalidateListObjectsPage(ctx context.Context, bucket, prefix, token string, page int, prevToken string) {
+	cacheKey := listObjectsCacheKey(bucket, prefix, token)
+	if _, loaded := c.revalidating.LoadOrStore(cacheKey, struct{}{}); loaded {
+		return
+	}
+
+	go func() {
+		defer c.revalidating.Delete(cacheKey)
+		_, err, _ := c.listObjectsGroup.Do(cacheKey, func() (interface{}, error) {
+			return c.fetchListObjectsPage(ctx, bucket, prefix, token, page, prevToken)
+		})
+		if err != nil {
+			slog.Warn("failed to refresh listObjects cache", "bucket", bucket, "prefix", prefix, "page", page, "error", err)
+		}
+	}()
+}
+
+// fetchListObjectsPage calls ListObjectsV2 for the page starting at the continuation token and saves the result
+// to the cache with its page number and the token of the previous page.
+// The later pages of the prefix are dropped when the first page is fetched, since they were listed with
+// continuation tokens of the old first page.
+func (c *Client) fetchListObjectsPage(ctx context.Context, bucket, prefix, token string, page int, prevToken string) (ListObjectsCacheEntry, error) {
+	// Add a trailing slash to the prefix if it doesn't already have one
+	s3Prefix := prefix
+	if s3Prefix != "" && !strings.HasSuffix(s3Prefix, "/") {
+		s3Prefix += "/"
 	}
 
 	input := &s3.ListObjectsV2Input{
 		Bucket:    aws.String(bucket),
-		Prefix:    aws.String(prefix),
+		Prefix:    aws.String(s3Prefix),
 		Delimiter: aws.String("/"),
 	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+	if c.PageSize > 0 {
+		input.MaxKeys = aws.Int32(c.PageSize)
+	}
 
+	c.metrics.listObjectsUpstreamCalls.Add(1)
 	result, err := c.s3Client.ListObjectsV2(ctx, input)
 	if err != nil {
-		return nil, false, fmt.Errorf("ListObjectsV2 operation failed for bucket %q: %w", bucket, err)
+		return ListObjectsCacheEntry{}, fmt.Errorf("ListObjectsV2 operation failed for bucket %q: %w", bucket, err)
 	}
 
 	// Save to cache
-	c.listObjectsCacheEntry[cacheKey] = ListObjectsCacheEntry{
-		data:   result,
-		Expiry: now.Add(c.CacheDuration),
+	now := time.Now()
+	entry := ListObjectsCacheEntry{
+		data:        result,
+		page:        page,
+		Expiry:      now.Add(c.CacheDuration),
+		StaleExpiry: now.Add(c.CacheDuration + c.StaleDuration),
+	}
+	if page > 1 {
+		entry.prevToken = prevToken
 	}
+	if token == "" {
+		c.listObjectsCache.DeletePrefix(listObjectsCacheKeyPrefix(bucket, prefix))
+	}
+	c.listObjectsCache.Set(listObjectsCacheKey(bucket, prefix, token), entry)
+
+	return entry, nil
+}
+
+// ErrTooManyObjects is returned by ListAllObjects when the number of objects exceeds the limit.
+var ErrTooManyObjects = errors.New("too many objects")
+
+// ObjectSummary contains the key, size and modification time of an S3 object.
+type ObjectSummary struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ListAllObjects recursively lists all objects under the specified prefix without using the cache.
+// Folder markers (keys ending with a slash) are skipped.
+// It fails with ErrTooManyObjects if there are more than maxObjects objects; maxObjects of 0 or less means no limit.
+func (c *Client) ListAllObjects(ctx context.Context, bucket, prefix string, maxObjects int) ([]ObjectSummary, error) {
+	paginator := s3.NewListObjectsV2Paginator(c.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
 
-	return convertToObjectInfo(result, prefix), false, nil
+	var objects []ObjectSummary
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ListObjectsV2 operation failed for bucket %q: %w", bucket, err)
+		}
+
+		for _, obj := range result.Contents {
+			if strings.HasSuffix(aws.ToString(obj.Key), "/") {
+				continue
+			}
+			if maxObjects > 0 && len(objects) >= maxObjects {
+				return nil, fmt.Errorf("%w: more than %d objects under %q in bucket %q", ErrTooManyObjects, maxObjects, prefix, bucket)
+			}
+			objects = append(objects, ObjectSummary{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+	return objects, nil
 }
 
 func convertToObjectInfo(result *s3.ListObjectsV2Output, prefix string) []ObjectInfo {
@@ -204,7 +481,7 @@ func convertToObjectInfo(result *s3.ListObjectsV2Output, prefix string) []Object
 		}
 
 		// Convert size to a string with SI prefixes
-		size := formatSize(*obj.Size)
+		size := FormatSize(*obj.Size)
 
 		objects = append(objects, ObjectInfo{
 			Name:         *obj.Key,
@@ -212,25 +489,86 @@ func convertToObjectInfo(result *s3.ListObjectsV2Output, prefix string) []Object
 			IsDirectory:  false,
 			Size:         size,
 			LastModified: *obj.LastModified,
+			ETag:         aws.ToString(obj.ETag),
 		})
 	}
 	return objects
 }
 
+// GetObjectOption customizes the GetObject request.
+type GetObjectOption func(*s3.GetObjectInput)
+
+// WithRange requests only the specified byte range (e.g. "bytes=0-1023") of the object.
+func WithRange(byteRange string) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if byteRange != "" {
+			input.Range = aws.String(byteRange)
+		}
+	}
+}
+
+// WithIfNoneMatch makes S3 respond with 304 Not Modified if the object's ETag matches.
+func WithIfNoneMatch(etag string) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if etag != "" {
+			input.IfNoneMatch = aws.String(etag)
+		}
+	}
+}
+
+// WithIfModifiedSince makes S3 respond with 304 Not Modified if the object has not been modified since t.
+func WithIfModifiedSince(t time.Time) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if !t.IsZero() {
+			input.IfModifiedSince = aws.Time(t)
+		}
+	}
+}
+
+// WithVersionID requests the specified version of the object instead of the latest one.
+func WithVersionID(versionID string) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if versionID != "" {
+			input.VersionId = aws.String(versionID)
+		}
+	}
+}
+
 // GetObject retrieves an object from the specified S3 bucket and key.
-func (c *Client) GetObject(ctx context.Context, bucket, key string) (*s3.GetObjectOutput, error) {
-	output, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+func (c *Client) GetObject(ctx context.Context, bucket, key string, opts ...GetObjectOption) (*s3.GetObjectOutput, error) {
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
-	})
+	}
+	for _, opt := range opts {
+		opt(input)
+	}
+
+	output, err := c.s3Client.GetObject(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("GetObject failed for bucket %q key %q: %w", bucket, key, err)
 	}
 	return output, nil
 }
 
-// formatSize converts a size in bytes to a human-readable string with SI prefixes.
-func formatSize(size int64) string {
+// ObjectSize returns the size of the specified version of an object, or of the latest version if versionID is empty.
+func (c *Client) ObjectSize(ctx context.Context, bucket, key, versionID string) (int64, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	output, err := c.s3Client.HeadObject(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("HeadObject failed for bucket %q key %q: %w", bucket, key, err)
+	}
+	return aws.ToInt64(output.ContentLength), nil
+}
+
+// FormatSize converts a size in bytes to a human-readable string with SI prefixes.
+func FormatSize(size int64) string {
 	var unit string
 	var value float64
 	switch {
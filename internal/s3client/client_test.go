@@ -3,6 +3,7 @@ package s3client
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,8 +19,23 @@ type MockS3Client struct {
 	listBucketsError  error
 	listObjectsOutput *s3.ListObjectsV2Output
 	listObjectsError  error
-	getObjectOutput   *s3.GetObjectOutput
-	getObjectError    error
+	// listObjectsPages maps a continuation token ("" for the first page) to its output
+	listObjectsPages map[string]*s3.ListObjectsV2Output
+	listObjectsCalls atomic.Int32
+	// listObjectsBlock, if set, blocks ListObjectsV2 until it is closed
+	listObjectsBlock chan struct{}
+	getObjectOutput  *s3.GetObjectOutput
+	getObjectError   error
+	getObjectInput   *s3.GetObjectInput
+	headObjectOutput *s3.HeadObjectOutput
+	headObjectError  error
+	headObjectInput  *s3.HeadObjectInput
+	getTaggingOutput *s3.GetObjectTaggingOutput
+	getTaggingError  error
+	// listVersionsPages maps a key marker ("" for the first page) to its output
+	listVersionsPages map[string]*s3.ListObjectVersionsOutput
+	listVersionsError error
+	listVersionsInput *s3.ListObjectVersionsInput
 }
 
 // ListBuckets mocks the ListBuckets method of S3Client
@@ -29,14 +45,43 @@ func (m *MockS3Client) ListBuckets(ctx context.Context, params *s3.ListBucketsIn
 
 // ListObjectsV2 mocks the ListObjectsV2 method of S3Client
 func (m *MockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.listObjectsCalls.Add(1)
+	if m.listObjectsBlock != nil {
+		<-m.listObjectsBlock
+	}
+	if m.listObjectsPages != nil {
+		output, found := m.listObjectsPages[aws.ToString(params.ContinuationToken)]
+		if !found {
+			return nil, errors.New("invalid continuation token")
+		}
+		return output, m.listObjectsError
+	}
 	return m.listObjectsOutput, m.listObjectsError
 }
 
 // GetObject mocks the GetObject method of S3Client
 func (m *MockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.getObjectInput = params
 	return m.getObjectOutput, m.getObjectError
 }
 
+// HeadObject mocks the HeadObject method of S3Client
+func (m *MockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	m.headObjectInput = params
+	return m.headObjectOutput, m.headObjectError
+}
+
+// GetObjectTagging mocks the GetObjectTagging method of S3Client
+func (m *MockS3Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return m.getTaggingOutput, m.getTaggingError
+}
+
+// ListObjectVersions mocks the ListObjectVersions method of S3Client
+func (m *MockS3Client) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	m.listVersionsInput = params
+	return m.listVersionsPages[aws.ToString(params.KeyMarker)], m.listVersionsError
+}
+
 // TestClient_ListBuckets tests the ListBuckets method of Client
 func TestClient_ListBuckets(t *testing.T) {
 	mockTime := time.Now()
@@ -80,7 +125,7 @@ func TestClient_ListBuckets(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := &Client{s3Client: tt.mock}
-			result, err := client.ListBuckets(context.Background())
+			result, _, err := client.ListBuckets(context.Background())
 
 			if tt.expectedErr != "" {
 				assert.ErrorContains(t, err, tt.expectedErr)
@@ -93,6 +138,38 @@ func TestClient_ListBuckets(t *testing.T) {
 	}
 }
 
+// TestClient_ListBuckets_Cache tests that ListBuckets results are cached until they expire
+func TestClient_ListBuckets_Cache(t *testing.T) {
+	mockTime := time.Now()
+	mock := &MockS3Client{
+		listBucketsOutput: &s3.ListBucketsOutput{
+			Buckets: []types.Bucket{{Name: aws.String("bucket1"), CreationDate: &mockTime}},
+		},
+	}
+	client := &Client{s3Client: mock, BucketsCacheDuration: time.Minute}
+	ctx := context.Background()
+
+	_, hitCache, err := client.ListBuckets(ctx)
+	assert.NoError(t, err)
+	assert.False(t, hitCache)
+	assert.NotNil(t, client.GetListBucketsCacheEntry(ctx))
+
+	// The cached result is returned even if the upstream changes
+	mock.listBucketsOutput = &s3.ListBucketsOutput{}
+	result, hitCache, err := client.ListBuckets(ctx)
+	assert.NoError(t, err)
+	assert.True(t, hitCache)
+	assert.Equal(t, []BucketInfo{{Name: "bucket1", CreationDate: mockTime}}, result)
+
+	// Clearing the cache fetches the buckets again
+	client.ClearListBucketsCache(ctx)
+	assert.Nil(t, client.GetListBucketsCacheEntry(ctx))
+	result, hitCache, err = client.ListBuckets(ctx)
+	assert.NoError(t, err)
+	assert.False(t, hitCache)
+	assert.Empty(t, result)
+}
+
 // TestClient_ListObjects tests the ListObjects method of Client
 func TestClient_ListObjects(t *testing.T) {
 	mockTime := time.Now()
@@ -120,6 +197,7 @@ func TestClient_ListObjects(t *testing.T) {
 							Key:          aws.String("test/prefix/file1.txt"),
 							Size:         aws.Int64(1024),
 							LastModified: &mockTime,
+							ETag:         aws.String(`"etag1"`),
 						},
 					},
 				},
@@ -141,6 +219,7 @@ func TestClient_ListObjects(t *testing.T) {
 					IsDirectory:  false,
 					Size:         "1.0 KB",
 					LastModified: mockTime,
+					ETag:         `"etag1"`,
 				},
 			},
 		},
@@ -157,14 +236,178 @@ func TestClient_ListObjects(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := &Client{s3Client: tt.mock, listObjectsCacheEntry: make(map[string]ListObjectsCacheEntry)}
-			result, _, err := client.ListObjects(context.Background(), tt.bucket, tt.prefix)
+			client := &Client{s3Client: tt.mock, listObjectsCache: NewMemoryCache()}
+			result, _, err := client.ListObjects(context.Background(), tt.bucket, tt.prefix, "", "")
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Objects)
+		})
+	}
+}
+
+// TestClient_ListObjects_Pagination tests that ListObjects lists the page of a continuation token and caches each page
+func TestClient_ListObjects_Pagination(t *testing.T) {
+	mockTime := time.Now()
+	newPage := func(key string, nextToken *string) *s3.ListObjectsV2Output {
+		return &s3.ListObjectsV2Output{
+			Contents: []types.Object{
+				{Key: aws.String(key), Size: aws.Int64(1), LastModified: &mockTime},
+			},
+			IsTruncated:           aws.Bool(nextToken != nil),
+			NextContinuationToken: nextToken,
+		}
+	}
+
+	// visit is a request of a page with the token of the page the link was on
+	type visit struct {
+		token     string
+		prevToken string
+	}
+
+	tests := []struct {
+		name              string
+		visits            []visit
+		expectedKey       string
+		expectedPage      int
+		expectedHasPrev   bool
+		expectedPrevToken string
+		expectedNextToken string
+		expectedCalls     int32
+		expectedErr       string
+	}{
+		{
+			name:              "正常系: 1ページ目",
+			visits:            []visit{{}},
+			expectedKey:       "dir/a.txt",
+			expectedPage:      1,
+			expectedNextToken: "token2",
+			expectedCalls:     1,
+		},
+		{
+			name:              "正常系: 前のページから辿った3ページ目",
+			visits:            []visit{{}, {token: "token2"}, {token: "token3", prevToken: "token2"}},
+			expectedKey:       "dir/c.txt",
+			expectedPage:      3,
+			expectedHasPrev:   true,
+			expectedPrevToken: "token2",
+			expectedCalls:     3,
+		},
+		{
+			name:          "正常系: 前のページが不明なページ",
+			visits:        []visit{{token: "token3", prevToken: "token2"}},
+			expectedKey:   "dir/c.txt",
+			expectedPage:  0,
+			expectedCalls: 1,
+		},
+		{
+			name:        "異常系: 不正な継続トークン",
+			visits:      []visit{{token: "invalid"}},
+			expectedErr: "invalid continuation token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{
+				listObjectsPages: map[string]*s3.ListObjectsV2Output{
+					"":       newPage("dir/a.txt", aws.String("token2")),
+					"token2": newPage("dir/b.txt", aws.String("token3")),
+					"token3": newPage("dir/c.txt", nil),
+				},
+			}
+			client := &Client{s3Client: mock, CacheDuration: time.Minute, listObjectsCache: NewMemoryCache()}
+			var result *ObjectsPage
+			var hitCache bool
+			var err error
+			for _, v := range tt.visits {
+				result, hitCache, err = client.ListObjects(context.Background(), "test-bucket", "dir", v.token, v.prevToken)
+			}
 
 			if tt.expectedErr != "" {
 				assert.ErrorContains(t, err, tt.expectedErr)
 				return
 			}
 
+			assert.NoError(t, err)
+			assert.False(t, hitCache)
+			assert.Equal(t, tt.expectedKey, result.Objects[0].Name)
+			assert.Equal(t, tt.expectedPage, result.Page)
+			assert.Equal(t, tt.expectedHasPrev, result.HasPrev)
+			assert.Equal(t, tt.expectedPrevToken, result.PrevToken)
+			assert.Equal(t, tt.expectedNextToken, result.NextToken)
+			assert.Equal(t, tt.expectedCalls, mock.listObjectsCalls.Load())
+
+			// The same page is served from the cache with the same page number
+			last := tt.visits[len(tt.visits)-1]
+			cached, hitCache, err := client.ListObjects(context.Background(), "test-bucket", "dir", last.token, "")
+			assert.NoError(t, err)
+			assert.True(t, hitCache)
+			assert.Equal(t, tt.expectedPage, cached.Page)
+			assert.Equal(t, tt.expectedCalls, mock.listObjectsCalls.Load())
+
+			// Clearing the cache drops every page of the prefix
+			client.ClearListObjectsCache(context.Background(), "test-bucket", "dir")
+			assert.Equal(t, 0, client.listObjectsCache.Len())
+		})
+	}
+}
+
+// TestClient_ListAllObjects tests the ListAllObjects method of Client
+func TestClient_ListAllObjects(t *testing.T) {
+	mockTime := time.Now()
+	pages := map[string]*s3.ListObjectsV2Output{
+		"": {
+			Contents: []types.Object{
+				{Key: aws.String("dir/"), Size: aws.Int64(0), LastModified: &mockTime},
+				{Key: aws.String("dir/a.txt"), Size: aws.Int64(1), LastModified: &mockTime},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("token2"),
+		},
+		"token2": {
+			Contents: []types.Object{
+				{Key: aws.String("dir/sub/b.txt"), Size: aws.Int64(2), LastModified: &mockTime},
+			},
+			IsTruncated: aws.Bool(false),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		maxObjects  int
+		expected    []ObjectSummary
+		expectedErr error
+	}{
+		{
+			name:       "正常系: すべてのページを再帰的に取得",
+			maxObjects: 0,
+			expected: []ObjectSummary{
+				{Key: "dir/a.txt", Size: 1, LastModified: mockTime},
+				{Key: "dir/sub/b.txt", Size: 2, LastModified: mockTime},
+			},
+		},
+		{
+			name:        "異常系: オブジェクト数の上限を超過",
+			maxObjects:  1,
+			expectedErr: ErrTooManyObjects,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{s3Client: &MockS3Client{listObjectsPages: pages}}
+			result, err := client.ListAllObjects(context.Background(), "test-bucket", "dir/", tt.maxObjects)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
@@ -214,7 +457,114 @@ func TestClient_GetObject(t *testing.T) {
 	}
 }
 
-// TestFormatSize tests the formatSize function with various size inputs.
+// TestClient_GetObject_Options tests that GetObject options are applied to the request
+func TestClient_GetObject_Options(t *testing.T) {
+	modifiedSince := time.Date(2025, 1, 26, 2, 5, 17, 0, time.UTC)
+
+	tests := []struct {
+		name                    string
+		opts                    []GetObjectOption
+		expectedRange           *string
+		expectedIfNoneMatch     *string
+		expectedIfModifiedSince *time.Time
+		expectedVersionID       *string
+	}{
+		{
+			name: "正常系: オプションなし",
+		},
+		{
+			name:          "正常系: 範囲指定",
+			opts:          []GetObjectOption{WithRange("bytes=0-1023")},
+			expectedRange: aws.String("bytes=0-1023"),
+		},
+		{
+			name: "正常系: 空の範囲指定は無視",
+			opts: []GetObjectOption{WithRange("")},
+		},
+		{
+			name:                    "正常系: 条件付き取得",
+			opts:                    []GetObjectOption{WithIfNoneMatch(`"etag"`), WithIfModifiedSince(modifiedSince)},
+			expectedIfNoneMatch:     aws.String(`"etag"`),
+			expectedIfModifiedSince: &modifiedSince,
+		},
+		{
+			name: "正常系: 空の条件は無視",
+			opts: []GetObjectOption{WithIfNoneMatch(""), WithIfModifiedSince(time.Time{})},
+		},
+		{
+			name:              "正常系: バージョン指定",
+			opts:              []GetObjectOption{WithVersionID("v1")},
+			expectedVersionID: aws.String("v1"),
+		},
+		{
+			name: "正常系: 空のバージョン指定は無視",
+			opts: []GetObjectOption{WithVersionID("")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{getObjectOutput: &s3.GetObjectOutput{}}
+			client := &Client{s3Client: mock}
+			_, err := client.GetObject(context.Background(), "test-bucket", "test-key", tt.opts...)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "test-key", aws.ToString(mock.getObjectInput.Key))
+			assert.Equal(t, tt.expectedRange, mock.getObjectInput.Range)
+			assert.Equal(t, tt.expectedIfNoneMatch, mock.getObjectInput.IfNoneMatch)
+			assert.Equal(t, tt.expectedIfModifiedSince, mock.getObjectInput.IfModifiedSince)
+			assert.Equal(t, tt.expectedVersionID, mock.getObjectInput.VersionId)
+		})
+	}
+}
+
+// TestClient_ObjectSize tests the ObjectSize method of Client
+func TestClient_ObjectSize(t *testing.T) {
+	tests := []struct {
+		name              string
+		versionID         string
+		mock              *MockS3Client
+		expected          int64
+		expectedVersionID *string
+		expectedErr       string
+	}{
+		{
+			name:     "正常系: 最新バージョンのサイズ",
+			mock:     &MockS3Client{headObjectOutput: &s3.HeadObjectOutput{ContentLength: aws.Int64(1024)}},
+			expected: 1024,
+		},
+		{
+			name:              "正常系: 指定したバージョンのサイズ",
+			versionID:         "v1",
+			mock:              &MockS3Client{headObjectOutput: &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}},
+			expected:          10,
+			expectedVersionID: aws.String("v1"),
+		},
+		{
+			name:        "異常系: 取得失敗",
+			mock:        &MockS3Client{headObjectError: errors.New("not found")},
+			expectedErr: "HeadObject failed for bucket \"test-bucket\" key \"test-key\": not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{s3Client: tt.mock}
+			size, err := client.ObjectSize(context.Background(), "test-bucket", "test-key", tt.versionID)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+			assert.Equal(t, tt.expectedVersionID, tt.mock.headObjectInput.VersionId)
+		})
+	}
+}
+
+// TestFormatSize tests the FormatSize function with various size inputs.
 func TestFormatSize(t *testing.T) {
 	tests := []struct {
 		input    int64
@@ -231,8 +581,101 @@ func TestFormatSize(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.expected, func(t *testing.T) {
-			result := formatSize(tt.input)
+			result := FormatSize(tt.input)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
+
+// TestClient_ListObjects_StaleWhileRevalidate tests that stale cache entries are served while refreshed in the background
+func TestClient_ListObjects_StaleWhileRevalidate(t *testing.T) {
+	mockTime := time.Now()
+	cached := &s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("dir/old.txt"), Size: aws.Int64(1), LastModified: &mockTime}},
+	}
+	fresh := &s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("dir/new.txt"), Size: aws.Int64(1), LastModified: &mockTime}},
+	}
+
+	tests := []struct {
+		name               string
+		entry              ListObjectsCacheEntry
+		expectedKey        string
+		expectedHitCache   bool
+		expectedRefreshing bool
+	}{
+		{
+			name:             "正常系: 有効期限内のキャッシュ",
+			entry:            ListObjectsCacheEntry{data: cached, Expiry: mockTime.Add(time.Minute), StaleExpiry: mockTime.Add(time.Hour)},
+			expectedKey:      "dir/old.txt",
+			expectedHitCache: true,
+		},
+		{
+			name:               "正常系: 期限切れのキャッシュを返しつつバックグラウンドで更新",
+			entry:              ListObjectsCacheEntry{data: cached, Expiry: mockTime.Add(-time.Minute), StaleExpiry: mockTime.Add(time.Hour)},
+			expectedKey:        "dir/old.txt",
+			expectedHitCache:   true,
+			expectedRefreshing: true,
+		},
+		{
+			name:        "正常系: ハード期限切れのキャッシュはブロックして再取得",
+			entry:       ListObjectsCacheEntry{data: cached, Expiry: mockTime.Add(-time.Hour), StaleExpiry: mockTime.Add(-time.Minute)},
+			expectedKey: "dir/new.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{listObjectsOutput: fresh}
+			client := &Client{s3Client: mock, CacheDuration: time.Minute, StaleDuration: time.Hour, listObjectsCache: NewMemoryCache()}
+			client.listObjectsCache.Set(listObjectsCacheKey("test-bucket", "dir", ""), tt.entry)
+
+			result, hitCache, err := client.ListObjects(context.Background(), "test-bucket", "dir", "", "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedKey, result.Objects[0].Name)
+			assert.Equal(t, tt.expectedHitCache, hitCache)
+			assert.Equal(t, tt.expectedRefreshing, result.Refreshing)
+
+			if tt.expectedRefreshing {
+				// The background refresh replaces the stale entry with a fresh one
+				assert.Eventually(t, func() bool {
+					entry := client.GetListObjectsCacheEntry(context.Background(), "test-bucket", "dir", "")
+					return entry != nil && entry.Expiry.After(time.Now())
+				}, time.Second, time.Millisecond)
+				assert.Equal(t, int32(1), mock.listObjectsCalls.Load())
+
+				result, hitCache, err = client.ListObjects(context.Background(), "test-bucket", "dir", "", "")
+				assert.NoError(t, err)
+				assert.True(t, hitCache)
+				assert.False(t, result.Refreshing)
+				assert.Equal(t, "dir/new.txt", result.Objects[0].Name)
+			}
+		})
+	}
+}
+
+// TestClient_ListObjects_RefreshFirstPage tests that refreshing the first page drops the later pages of the prefix
+func TestClient_ListObjects_RefreshFirstPage(t *testing.T) {
+	mockTime := time.Now()
+	page := &s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("dir/a.txt"), Size: aws.Int64(1), LastModified: &mockTime}},
+	}
+	mock := &MockS3Client{listObjectsOutput: page}
+	client := &Client{s3Client: mock, CacheDuration: time.Minute, StaleDuration: time.Hour, listObjectsCache: NewMemoryCache()}
+	stale := ListObjectsCacheEntry{data: page, page: 1, Expiry: mockTime.Add(-time.Minute), StaleExpiry: mockTime.Add(time.Hour)}
+	client.listObjectsCache.Set(listObjectsCacheKey("test-bucket", "dir", ""), stale)
+	client.listObjectsCache.Set(listObjectsCacheKey("test-bucket", "dir", "token2"), ListObjectsCacheEntry{data: page, page: 2, Expiry: mockTime.Add(time.Minute)})
+	client.listObjectsCache.Set(listObjectsCacheKey("test-bucket", "dir2", "token2"), ListObjectsCacheEntry{data: page, page: 2, Expiry: mockTime.Add(time.Minute)})
+
+	_, _, err := client.ListObjects(context.Background(), "test-bucket", "dir", "", "")
+	assert.NoError(t, err)
+
+	// The later page of the prefix is dropped by the background refresh, but not the page of another prefix
+	assert.Eventually(t, func() bool {
+		entry := client.GetListObjectsCacheEntry(context.Background(), "test-bucket", "dir", "")
+		return entry != nil && entry.Expiry.After(time.Now())
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, client.GetListObjectsCacheEntry(context.Background(), "test-bucket", "dir", "token2"))
+	assert.Equal(t, 1, client.GetListObjectsCacheEntry(context.Background(), "test-bucket", "dir", "").page)
+	assert.NotNil(t, client.GetListObjectsCacheEntry(context.Background(), "test-bucket", "dir2", "token2"))
+}
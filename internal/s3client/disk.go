@@ -0,0 +1,181 @@
+package s3client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// diskCacheBucket is the name of the bbolt bucket holding the listObjects cache entries.
+var diskCacheBucket = []byte("listObjects")
+
+// diskCache is a listObjects cache persisted to a local bbolt file so that it survives restarts.
+type diskCache struct {
+	db *bolt.DB
+}
+
+// diskCacheRecord is the serialized form of a ListObjectsCacheEntry.
+type diskCacheRecord struct {
+	Expiry      time.Time               `json:"expiry"`
+	StaleExpiry time.Time               `json:"stale_expiry"`
+	Data        *s3.ListObjectsV2Output `json:"data"`
+	Page        int                     `json:"page,omitempty"`
+	PrevToken   string                  `json:"prev_token,omitempty"`
+}
+
+// entry converts the record back to a ListObjectsCacheEntry.
+func (r diskCacheRecord) entry() ListObjectsCacheEntry {
+	return ListObjectsCacheEntry{data: r.Data, page: r.Page, prevToken: r.PrevToken, Expiry: r.Expiry, StaleExpiry: r.StaleExpiry}
+}
+
+// NewDiskCache opens or creates the listObjects cache file at the specified path.
+func NewDiskCache(path string) (ListObjectsCache, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open disk cache %q: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(diskCacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize disk cache %q: %w", path, err)
+	}
+
+	return &diskCache{db: db}, nil
+}
+
+// Get returns the entry for the specified key.
+func (c *diskCache) Get(key string) (ListObjectsCacheEntry, bool) {
+	var record diskCacheRecord
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(diskCacheBucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &record)
+	})
+	if err != nil {
+		slog.Warn("failed to read disk cache entry", "key", key, "error", err)
+		return ListObjectsCacheEntry{}, false
+	}
+	if !found {
+		return ListObjectsCacheEntry{}, false
+	}
+	return record.entry(), true
+}
+
+// Set stores the entry for the specified key.
+func (c *diskCache) Set(key string, entry ListObjectsCacheEntry) {
+	value, err := json.Marshal(diskCacheRecord{
+		Expiry:      entry.Expiry,
+		StaleExpiry: entry.StaleExpiry,
+		Data:        entry.data,
+		Page:        entry.page,
+		PrevToken:   entry.prevToken,
+	})
+	if err != nil {
+		slog.Warn("failed to encode disk cache entry", "key", key, "error", err)
+		return
+	}
+
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(diskCacheBucket).Put([]byte(key), value)
+	})
+	if err != nil {
+		slog.Warn("failed to write disk cache entry", "key", key, "error", err)
+	}
+}
+
+// DeletePrefix removes all entries whose key starts with the specified prefix.
+func (c *diskCache) DeletePrefix(keyPrefix string) {
+	prefix := []byte(keyPrefix)
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(diskCacheBucket).Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Seek(prefix) {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to delete disk cache entries", "prefix", keyPrefix, "error", err)
+	}
+}
+
+// DeleteExpired removes all entries that have expired at the specified time.
+func (c *diskCache) DeleteExpired(now time.Time) {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(diskCacheBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var record diskCacheRecord
+			// Entries that can no longer be decoded are removed as well
+			if err := json.Unmarshal(v, &record); err != nil || record.entry().expired(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to delete expired disk cache entries", "error", err)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *diskCache) Len() int {
+	n := 0
+	err := c.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(diskCacheBucket).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to count disk cache entries", "error", err)
+	}
+	return n
+}
+
+// Range calls fn for each entry in key order until fn returns false.
+// Entries that can no longer be decoded are skipped.
+func (c *diskCache) Range(fn func(key string, entry ListObjectsCacheEntry) bool) {
+	err := c.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(diskCacheBucket).Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var record diskCacheRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				continue
+			}
+			if !fn(string(k), record.entry()) {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to iterate disk cache entries", "error", err)
+	}
+}
+
+// Close closes the underlying cache file.
+func (c *diskCache) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,56 @@
+package s3client
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiskCache_Persistence tests that entries and their expiry survive reopening the cache file
+func TestDiskCache_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	mockTime := time.Date(2025, 1, 26, 2, 5, 17, 0, time.UTC)
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := ListObjectsCacheEntry{
+		data: &s3.ListObjectsV2Output{
+			CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("dir/sub/")}},
+			Contents: []types.Object{
+				{Key: aws.String("dir/file.txt"), Size: aws.Int64(1024), LastModified: &mockTime},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("token3"),
+		},
+		page:      3,
+		prevToken: "token1",
+		Expiry:    expiry,
+	}
+
+	cache, err := NewDiskCache(path)
+	assert.NoError(t, err)
+	cache.Set("bucket/dir?token=token2", entry)
+	assert.NoError(t, cache.(*diskCache).Close())
+
+	cache, err = NewDiskCache(path)
+	assert.NoError(t, err)
+	defer cache.(*diskCache).Close()
+
+	assert.Equal(t, 1, cache.Len())
+	restored, found := cache.Get("bucket/dir?token=token2")
+	assert.True(t, found)
+	assert.True(t, expiry.Equal(restored.Expiry))
+	assert.Equal(t, convertToObjectInfo(entry.data, "dir/"), convertToObjectInfo(restored.data, "dir/"))
+	assert.Equal(t, "token3", aws.ToString(restored.data.NextContinuationToken))
+	assert.Equal(t, 3, restored.page)
+	assert.Equal(t, "token1", restored.prevToken)
+}
+
+// TestNewDiskCache_Error tests that opening a cache file in a missing directory fails
+func TestNewDiskCache_Error(t *testing.T) {
+	_, err := NewDiskCache(filepath.Join(t.TempDir(), "missing", "cache.db"))
+	assert.ErrorContains(t, err, "failed to open disk cache")
+}
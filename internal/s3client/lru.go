@@ -0,0 +1,57 @@
+package s3client
+
+import (
+	"strings"
+	"time"
+
+	"github.com/korosuke613/polybuckets/internal/lru"
+)
+
+// lruCache is an in-memory listObjects cache bounded by entry count and approximate bytes.
+// The least recently used entries are evicted first when a bound is exceeded.
+type lruCache struct {
+	entries *lru.Cache[ListObjectsCacheEntry]
+}
+
+// NewLRUCache creates an empty LRU listObjects cache.
+// maxEntries and maxBytes bound the cache; a value of 0 or less disables that bound.
+func NewLRUCache(maxEntries int, maxBytes int64) ListObjectsCache {
+	return &lruCache{
+		entries: lru.New(maxEntries, maxBytes, ListObjectsCacheEntry.Size),
+	}
+}
+
+// Get returns the entry for the specified key and marks it as recently used.
+func (c *lruCache) Get(key string) (ListObjectsCacheEntry, bool) {
+	return c.entries.Get(key)
+}
+
+// Set stores the entry for the specified key and evicts entries until the bounds are satisfied.
+func (c *lruCache) Set(key string, entry ListObjectsCacheEntry) {
+	c.entries.Set(key, entry)
+}
+
+// DeletePrefix removes all entries whose key starts with the specified prefix.
+func (c *lruCache) DeletePrefix(keyPrefix string) {
+	c.entries.DeleteFunc(func(key string, entry ListObjectsCacheEntry) bool {
+		return strings.HasPrefix(key, keyPrefix)
+	})
+}
+
+// DeleteExpired removes all entries that have expired at the specified time.
+func (c *lruCache) DeleteExpired(now time.Time) {
+	c.entries.DeleteFunc(func(key string, entry ListObjectsCacheEntry) bool {
+		return entry.expired(now)
+	})
+}
+
+// Len returns the number of entries in the cache.
+func (c *lruCache) Len() int {
+	return c.entries.Len()
+}
+
+// Range calls fn for each entry from the most recently used until fn returns false.
+// It does not change the recency of the entries.
+func (c *lruCache) Range(fn func(key string, entry ListObjectsCacheEntry) bool) {
+	c.entries.Range(fn)
+}
@@ -0,0 +1,71 @@
+package s3client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLRUCache_MaxEntries tests that the least recently used entry is evicted when the entry count is exceeded
+func TestLRUCache_MaxEntries(t *testing.T) {
+	expiry := time.Now().Add(time.Minute)
+	cache := NewLRUCache(2, 0)
+	cache.Set("a", ListObjectsCacheEntry{Expiry: expiry})
+	cache.Set("b", ListObjectsCacheEntry{Expiry: expiry})
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	_, found := cache.Get("a")
+	assert.True(t, found)
+
+	cache.Set("c", ListObjectsCacheEntry{Expiry: expiry})
+	assert.Equal(t, 2, cache.Len())
+	_, found = cache.Get("b")
+	assert.False(t, found)
+	_, found = cache.Get("a")
+	assert.True(t, found)
+	_, found = cache.Get("c")
+	assert.True(t, found)
+}
+
+// TestLRUCache_MaxBytes tests that entries are evicted when the approximate size is exceeded
+func TestLRUCache_MaxBytes(t *testing.T) {
+	expiry := time.Now().Add(time.Minute)
+	newEntry := func(keys int) ListObjectsCacheEntry {
+		output := &s3.ListObjectsV2Output{}
+		for i := 0; i < keys; i++ {
+			output.Contents = append(output.Contents, types.Object{Key: aws.String("dir/file.txt")})
+		}
+		return ListObjectsCacheEntry{data: output, Expiry: expiry}
+	}
+
+	small := newEntry(1)
+	cache := NewLRUCache(0, 3*small.Size()).(*lruCache)
+	cache.Set("a", small)
+	cache.Set("b", small)
+	cache.Set("c", small)
+	assert.Equal(t, 3, cache.Len())
+	assert.Equal(t, 3*small.Size(), cache.entries.Bytes())
+
+	// A larger entry evicts the least recently used entries until it fits
+	large := newEntry(3)
+	cache.Set("d", large)
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	_, found = cache.Get("d")
+	assert.True(t, found)
+	assert.LessOrEqual(t, cache.entries.Bytes(), 3*small.Size())
+
+	// An entry larger than the whole cache is not kept
+	cache.Set("e", newEntry(100))
+	_, found = cache.Get("e")
+	assert.False(t, found)
+
+	// Deleting entries releases their bytes
+	cache.DeletePrefix("")
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, int64(0), cache.entries.Bytes())
+}
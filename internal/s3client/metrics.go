@@ -0,0 +1,41 @@
+package s3client
+
+import "sync/atomic"
+
+// clientMetrics holds the counters of Client that are updated concurrently.
+type clientMetrics struct {
+	listObjectsUpstreamCalls atomic.Int64
+	listObjectsCoalesced     atomic.Int64
+	listObjectsCacheHits     atomic.Int64
+	listObjectsCacheMisses   atomic.Int64
+	listBucketsCacheHits     atomic.Int64
+	listBucketsCacheMisses   atomic.Int64
+}
+
+// Metrics is a point-in-time snapshot of the Client counters.
+type Metrics struct {
+	// ListObjectsUpstreamCalls is the number of ListObjectsV2 calls issued to S3.
+	ListObjectsUpstreamCalls int64 `json:"list_objects_upstream_calls"`
+	// ListObjectsCoalesced is the number of ListObjectsV2 calls saved by sharing an in-flight call.
+	ListObjectsCoalesced int64 `json:"list_objects_coalesced"`
+	// ListObjectsCacheHits is the number of ListObjects requests served from the cache.
+	ListObjectsCacheHits int64 `json:"list_objects_cache_hits"`
+	// ListObjectsCacheMisses is the number of ListObjects requests not served from the cache.
+	ListObjectsCacheMisses int64 `json:"list_objects_cache_misses"`
+	// ListBucketsCacheHits is the number of ListBuckets requests served from the cache.
+	ListBucketsCacheHits int64 `json:"list_buckets_cache_hits"`
+	// ListBucketsCacheMisses is the number of ListBuckets requests not served from the cache.
+	ListBucketsCacheMisses int64 `json:"list_buckets_cache_misses"`
+}
+
+// Metrics returns a snapshot of the client counters.
+func (c *Client) Metrics() Metrics {
+	return Metrics{
+		ListObjectsUpstreamCalls: c.metrics.listObjectsUpstreamCalls.Load(),
+		ListObjectsCoalesced:     c.metrics.listObjectsCoalesced.Load(),
+		ListObjectsCacheHits:     c.metrics.listObjectsCacheHits.Load(),
+		ListObjectsCacheMisses:   c.metrics.listObjectsCacheMisses.Load(),
+		ListBucketsCacheHits:     c.metrics.listBucketsCacheHits.Load(),
+		ListBucketsCacheMisses:   c.metrics.listBucketsCacheMisses.Load(),
+	}
+}
@@ -0,0 +1,107 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectDetail contains the metadata of an S3 object returned by HeadObject.
+type ObjectDetail struct {
+	Key                string
+	Size               int64
+	LastModified       time.Time
+	ContentType        string
+	ContentEncoding    string
+	ContentDisposition string
+	CacheControl       string
+	ETag               string
+	// StorageClass is STANDARD if S3 does not return it.
+	StorageClass         string
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+	BucketKeyEnabled     bool
+	// Checksums maps a checksum algorithm (e.g. "SHA256") to the base64-encoded checksum.
+	Checksums    map[string]string
+	ChecksumType string
+	// Metadata is the user-defined metadata (x-amz-meta-*) without the prefix.
+	Metadata          map[string]string
+	VersionID         string
+	LegalHold         string
+	RetentionMode     string
+	RetainUntil       time.Time
+	ReplicationStatus string
+}
+
+// HeadObject retrieves the metadata of an object including its checksums.
+func (c *Client) HeadObject(ctx context.Context, bucket, key string) (*ObjectDetail, error) {
+	output, err := c.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("HeadObject failed for bucket %q key %q: %w", bucket, key, err)
+	}
+
+	detail := &ObjectDetail{
+		Key:                  key,
+		Size:                 aws.ToInt64(output.ContentLength),
+		LastModified:         aws.ToTime(output.LastModified),
+		ContentType:          aws.ToString(output.ContentType),
+		ContentEncoding:      aws.ToString(output.ContentEncoding),
+		ContentDisposition:   aws.ToString(output.ContentDisposition),
+		CacheControl:         aws.ToString(output.CacheControl),
+		ETag:                 aws.ToString(output.ETag),
+		StorageClass:         string(output.StorageClass),
+		ServerSideEncryption: string(output.ServerSideEncryption),
+		SSEKMSKeyID:          aws.ToString(output.SSEKMSKeyId),
+		BucketKeyEnabled:     aws.ToBool(output.BucketKeyEnabled),
+		Checksums:            map[string]string{},
+		ChecksumType:         string(output.ChecksumType),
+		Metadata:             output.Metadata,
+		VersionID:            aws.ToString(output.VersionId),
+		LegalHold:            string(output.ObjectLockLegalHoldStatus),
+		RetentionMode:        string(output.ObjectLockMode),
+		RetainUntil:          aws.ToTime(output.ObjectLockRetainUntilDate),
+		ReplicationStatus:    string(output.ReplicationStatus),
+	}
+	// S3 returns the storage class only for objects not in STANDARD
+	if detail.StorageClass == "" {
+		detail.StorageClass = string(types.StorageClassStandard)
+	}
+	checksums := map[string]*string{
+		"CRC32":     output.ChecksumCRC32,
+		"CRC32C":    output.ChecksumCRC32C,
+		"CRC64NVME": output.ChecksumCRC64NVME,
+		"SHA1":      output.ChecksumSHA1,
+		"SHA256":    output.ChecksumSHA256,
+	}
+	for algorithm, checksum := range checksums {
+		if checksum != nil {
+			detail.Checksums[algorithm] = *checksum
+		}
+	}
+	return detail, nil
+}
+
+// GetObjectTags retrieves the tags of an object.
+func (c *Client) GetObjectTags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	output, err := c.s3Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetObjectTagging failed for bucket %q key %q: %w", bucket, key, err)
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
@@ -0,0 +1,115 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_HeadObject tests retrieving the metadata of an object
+func TestClient_HeadObject(t *testing.T) {
+	lastModified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	retainUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		output      *s3.HeadObjectOutput
+		err         error
+		expected    *ObjectDetail
+		expectedErr string
+	}{
+		{
+			name: "正常系: 全ての項目",
+			output: &s3.HeadObjectOutput{
+				ContentLength:             aws.Int64(1024),
+				LastModified:              &lastModified,
+				ContentType:               aws.String("text/plain"),
+				ETag:                      aws.String(`"abc"`),
+				StorageClass:              types.StorageClassGlacierIr,
+				ServerSideEncryption:      types.ServerSideEncryptionAwsKms,
+				SSEKMSKeyId:               aws.String("arn:aws:kms:key"),
+				BucketKeyEnabled:          aws.Bool(true),
+				ChecksumSHA256:            aws.String("c2hhMjU2"),
+				ChecksumType:              types.ChecksumTypeFullObject,
+				Metadata:                  map[string]string{"owner": "team-a"},
+				VersionId:                 aws.String("v1"),
+				ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+				ObjectLockMode:            types.ObjectLockModeGovernance,
+				ObjectLockRetainUntilDate: &retainUntil,
+			},
+			expected: &ObjectDetail{
+				Key:                  "test-key",
+				Size:                 1024,
+				LastModified:         lastModified,
+				ContentType:          "text/plain",
+				ETag:                 `"abc"`,
+				StorageClass:         "GLACIER_IR",
+				ServerSideEncryption: "aws:kms",
+				SSEKMSKeyID:          "arn:aws:kms:key",
+				BucketKeyEnabled:     true,
+				Checksums:            map[string]string{"SHA256": "c2hhMjU2"},
+				ChecksumType:         "FULL_OBJECT",
+				Metadata:             map[string]string{"owner": "team-a"},
+				VersionID:            "v1",
+				LegalHold:            "ON",
+				RetentionMode:        "GOVERNANCE",
+				RetainUntil:          retainUntil,
+			},
+		},
+		{
+			name:   "正常系: ストレージクラスが省略された場合はSTANDARD",
+			output: &s3.HeadObjectOutput{ContentLength: aws.Int64(0)},
+			expected: &ObjectDetail{
+				Key:          "test-key",
+				StorageClass: "STANDARD",
+				Checksums:    map[string]string{},
+			},
+		},
+		{
+			name:        "異常系: HeadObjectが失敗",
+			err:         errors.New("not found"),
+			expectedErr: `HeadObject failed for bucket "test-bucket" key "test-key": not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{headObjectOutput: tt.output, headObjectError: tt.err}
+			client := &Client{s3Client: mock}
+			detail, err := client.HeadObject(context.Background(), "test-bucket", "test-key")
+
+			assert.Equal(t, types.ChecksumModeEnabled, mock.headObjectInput.ChecksumMode)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, detail)
+		})
+	}
+}
+
+// TestClient_GetObjectTags tests retrieving the tags of an object
+func TestClient_GetObjectTags(t *testing.T) {
+	mock := &MockS3Client{getTaggingOutput: &s3.GetObjectTaggingOutput{
+		TagSet: []types.Tag{
+			{Key: aws.String("env"), Value: aws.String("prod")},
+			{Key: aws.String("team"), Value: aws.String("")},
+		},
+	}}
+	client := &Client{s3Client: mock}
+
+	tags, err := client.GetObjectTags(context.Background(), "test-bucket", "test-key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": ""}, tags)
+
+	mock.getTaggingError = errors.New("access denied")
+	_, err = client.GetObjectTags(context.Background(), "test-bucket", "test-key")
+	assert.EqualError(t, err, `GetObjectTagging failed for bucket "test-bucket" key "test-key": access denied`)
+}
@@ -0,0 +1,84 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/korosuke613/polybuckets/internal/env"
+)
+
+// ErrPresignUnavailable is returned when no endpoint reachable by clients is known for presigned URLs.
+var ErrPresignUnavailable = errors.New("presigned URLs are unavailable")
+
+// S3Presigner defines the operations required for presigning S3 requests.
+type S3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// newPresigner creates a presigner for the endpoint that clients use to reach S3.
+// It returns nil when AWS_ENDPOINT is set without PB_PRESIGN_ENDPOINT,
+// since a custom endpoint is often only reachable from polybuckets.
+func newPresigner(cfg aws.Config, pbConfig *env.PBConfigType) S3Presigner {
+	endpoint := pbConfig.PresignEndpoint
+	if endpoint == "" && pbConfig.AWSEndpoint != "" {
+		return nil
+	}
+	return s3.NewPresignClient(s3.NewFromConfig(cfg, withEndpoint(endpoint)))
+}
+
+// WithPresigner injects a custom presigner.
+func WithPresigner(presigner S3Presigner) ClientOption {
+	return func(c *Client) error {
+		c.presigner = presigner
+		return nil
+	}
+}
+
+// WithResponseContentDisposition overrides the Content-Disposition header of the response.
+func WithResponseContentDisposition(contentDisposition string) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if contentDisposition != "" {
+			input.ResponseContentDisposition = aws.String(contentDisposition)
+		}
+	}
+}
+
+// WithResponseCacheControl overrides the Cache-Control header of the response.
+func WithResponseCacheControl(cacheControl string) GetObjectOption {
+	return func(input *s3.GetObjectInput) {
+		if cacheControl != "" {
+			input.ResponseCacheControl = aws.String(cacheControl)
+		}
+	}
+}
+
+// CanPresign reports whether the client can create presigned URLs.
+func (c *Client) CanPresign() bool {
+	return c.presigner != nil
+}
+
+// PresignGetObject creates a presigned GET URL of the specified object that is valid for expiry.
+func (c *Client) PresignGetObject(ctx context.Context, bucket, key string, expiry time.Duration, opts ...GetObjectOption) (string, error) {
+	if c.presigner == nil {
+		return "", ErrPresignUnavailable
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	for _, opt := range opts {
+		opt(input)
+	}
+
+	request, err := c.presigner.PresignGetObject(ctx, input, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("PresignGetObject failed for bucket %q key %q: %w", bucket, key, err)
+	}
+	return request.URL, nil
+}
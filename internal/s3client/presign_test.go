@@ -0,0 +1,70 @@
+package s3client
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_PresignGetObject tests presigning with the endpoint reachable by clients
+func TestClient_PresignGetObject(t *testing.T) {
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("minioadmin", "minioadmin", ""),
+	}
+
+	tests := []struct {
+		name         string
+		pbConfig     *env.PBConfigType
+		expectedHost string
+		expectedErr  error
+	}{
+		{
+			name:         "正常系: 公開エンドポイントで署名",
+			pbConfig:     &env.PBConfigType{AWSEndpoint: "minio:9000", PresignEndpoint: "https://s3.example.com"},
+			expectedHost: "s3.example.com",
+		},
+		{
+			name:         "正常系: AWSのエンドポイントで署名",
+			pbConfig:     &env.PBConfigType{},
+			expectedHost: "test-bucket.s3.us-east-1.amazonaws.com",
+		},
+		{
+			name:        "異常系: クライアントから到達できるエンドポイントが不明",
+			pbConfig:    &env.PBConfigType{AWSEndpoint: "minio:9000"},
+			expectedErr: ErrPresignUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{presigner: newPresigner(cfg, tt.pbConfig)}
+			presignedURL, err := client.PresignGetObject(context.Background(), "test-bucket", "dir/日本語.txt", time.Minute,
+				WithResponseContentDisposition(`attachment; filename="test.txt"`),
+				WithResponseCacheControl(""),
+			)
+
+			if tt.expectedErr != nil {
+				assert.False(t, client.CanPresign())
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, client.CanPresign())
+			u, err := url.Parse(presignedURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedHost, u.Host)
+			assert.Contains(t, u.Path, "dir/")
+			assert.Equal(t, "60", u.Query().Get("X-Amz-Expires"))
+			assert.Equal(t, `attachment; filename="test.txt"`, u.Query().Get("response-content-disposition"))
+			assert.False(t, u.Query().Has("response-cache-control"))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// ObjectChunk is a part of an object read with a Range request.
+type ObjectChunk struct {
+	Data []byte
+	// Offset is the position of Data in the object.
+	Offset int64
+	// Size is the total size of the object, or -1 if unknown.
+	Size            int64
+	ContentType     string
+	ContentEncoding string
+	ETag            string
+	LastModified    time.Time
+}
+
+// Truncated reports whether the object has more bytes than the chunk.
+func (c *ObjectChunk) Truncated() bool {
+	return c.Offset > 0 || c.Size < 0 || c.Offset+int64(len(c.Data)) < c.Size
+}
+
+// ReadObjectRange reads at most length bytes of an object starting at offset.
+// A negative offset reads the last length bytes of the object.
+// If S3 ignores the Range header and returns the whole object, the chunk is cut out of it.
+// opts customize the request, e.g. with conditions.
+func (c *Client) ReadObjectRange(ctx context.Context, bucket, key string, offset, length int64, opts ...GetObjectOption) (*ObjectChunk, error) {
+	byteRange := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	if offset < 0 {
+		byteRange = fmt.Sprintf("bytes=-%d", length)
+	}
+
+	output, err := c.GetObject(ctx, bucket, key, append(opts, WithRange(byteRange))...)
+	if err != nil {
+		// S3 does not satisfy any range of an empty object
+		var respErr *awshttp.ResponseError
+		if offset <= 0 && errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusRequestedRangeNotSatisfiable {
+			return &ObjectChunk{Data: []byte{}}, nil
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	chunk := &ObjectChunk{
+		Size:            aws.ToInt64(output.ContentLength),
+		ContentType:     aws.ToString(output.ContentType),
+		ContentEncoding: aws.ToString(output.ContentEncoding),
+		ETag:            aws.ToString(output.ETag),
+		LastModified:    aws.ToTime(output.LastModified),
+	}
+	if output.ContentRange != nil {
+		start, size, ok := parseContentRange(*output.ContentRange)
+		if !ok {
+			return nil, fmt.Errorf("invalid Content-Range %q for bucket %q key %q", *output.ContentRange, bucket, key)
+		}
+		chunk.Offset = start
+		chunk.Size = size
+	} else {
+		// The whole object is returned, so skip to the requested position
+		chunk.Offset = offset
+		if offset < 0 {
+			chunk.Offset = max(chunk.Size-length, 0)
+		}
+		if _, err := io.CopyN(io.Discard, output.Body, chunk.Offset); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read bucket %q key %q: %w", bucket, key, err)
+		}
+	}
+
+	chunk.Data, err = io.ReadAll(io.LimitReader(output.Body, length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bucket %q key %q: %w", bucket, key, err)
+	}
+	return chunk, nil
+}
+
+// parseContentRange parses a Content-Range header value such as "bytes 0-1023/4096".
+// The size is -1 if it is unknown ("*").
+func parseContentRange(contentRange string) (start, size int64, ok bool) {
+	spec, found := strings.CutPrefix(contentRange, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, sizeString, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	startString, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startString, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if sizeString == "*" {
+		return start, -1, true
+	}
+	size, err = strconv.ParseInt(sizeString, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// ObjectReader reads parts of an object with Range requests. It implements io.ReaderAt.
+type ObjectReader struct {
+	ctx    context.Context
+	client *Client
+	bucket string
+	key    string
+}
+
+// NewObjectReader returns an ObjectReader of the object that makes requests with ctx.
+func (c *Client) NewObjectReader(ctx context.Context, bucket, key string) *ObjectReader {
+	return &ObjectReader{ctx: ctx, client: c, bucket: bucket, key: key}
+}
+
+// ReadAt reads len(p) bytes of the object starting at off. It returns io.EOF if the object ends before.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk, err := r.client.ReadObjectRange(r.ctx, r.bucket, r.key, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, chunk.Data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,187 @@
+package s3client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_ReadObjectRange tests reading a part of an object
+func TestClient_ReadObjectRange(t *testing.T) {
+	tests := []struct {
+		name              string
+		offset            int64
+		length            int64
+		output            *s3.GetObjectOutput
+		err               error
+		expectedRange     string
+		expectedData      string
+		expectedOffset    int64
+		expectedSize      int64
+		expectedTruncated bool
+	}{
+		{
+			name:   "正常系: 先頭を取得",
+			offset: 0,
+			length: 4,
+			output: &s3.GetObjectOutput{
+				Body:          io.NopCloser(strings.NewReader("0123")),
+				ContentLength: aws.Int64(4),
+				ContentRange:  aws.String("bytes 0-3/10"),
+			},
+			expectedRange:     "bytes=0-3",
+			expectedData:      "0123",
+			expectedSize:      10,
+			expectedTruncated: true,
+		},
+		{
+			name:   "正常系: 範囲より小さいオブジェクト",
+			offset: 0,
+			length: 4,
+			output: &s3.GetObjectOutput{
+				Body:          io.NopCloser(strings.NewReader("01")),
+				ContentLength: aws.Int64(2),
+				ContentRange:  aws.String("bytes 0-1/2"),
+			},
+			expectedRange: "bytes=0-3",
+			expectedData:  "01",
+			expectedSize:  2,
+		},
+		{
+			name:   "正常系: 末尾を取得",
+			offset: -4,
+			length: 4,
+			output: &s3.GetObjectOutput{
+				Body:          io.NopCloser(strings.NewReader("6789")),
+				ContentLength: aws.Int64(4),
+				ContentRange:  aws.String("bytes 6-9/10"),
+			},
+			expectedRange:     "bytes=-4",
+			expectedData:      "6789",
+			expectedOffset:    6,
+			expectedSize:      10,
+			expectedTruncated: true,
+		},
+		{
+			name:   "正常系: Rangeが無視された場合は切り出す",
+			offset: -4,
+			length: 4,
+			output: &s3.GetObjectOutput{
+				Body:          io.NopCloser(strings.NewReader("0123456789")),
+				ContentLength: aws.Int64(10),
+			},
+			expectedRange:     "bytes=-4",
+			expectedData:      "6789",
+			expectedOffset:    6,
+			expectedSize:      10,
+			expectedTruncated: true,
+		},
+		{
+			name:   "正常系: 空のオブジェクト",
+			offset: 0,
+			length: 4,
+			err: &awshttp.ResponseError{
+				ResponseError: &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusRequestedRangeNotSatisfiable}},
+				},
+			},
+			expectedRange: "bytes=0-3",
+			expectedData:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{getObjectOutput: tt.output, getObjectError: tt.err}
+			client := &Client{s3Client: mock}
+			chunk, err := client.ReadObjectRange(context.Background(), "test-bucket", "test-key", tt.offset, tt.length)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRange, aws.ToString(mock.getObjectInput.Range))
+			assert.Equal(t, tt.expectedData, string(chunk.Data))
+			assert.Equal(t, tt.expectedOffset, chunk.Offset)
+			assert.Equal(t, tt.expectedSize, chunk.Size)
+			assert.Equal(t, tt.expectedTruncated, chunk.Truncated())
+		})
+	}
+}
+
+// TestObjectReader_ReadAt tests reading a part of an object as an io.ReaderAt
+func TestObjectReader_ReadAt(t *testing.T) {
+	tests := []struct {
+		name          string
+		length        int
+		output        *s3.GetObjectOutput
+		expectedRange string
+		expectedData  string
+		expectedErr   error
+	}{
+		{
+			name:   "正常系: 指定した長さを取得",
+			length: 4,
+			output: &s3.GetObjectOutput{
+				Body:         io.NopCloser(strings.NewReader("2345")),
+				ContentRange: aws.String("bytes 2-5/10"),
+			},
+			expectedRange: "bytes=2-5",
+			expectedData:  "2345",
+		},
+		{
+			name:   "正常系: 末尾に達した場合はEOF",
+			length: 10,
+			output: &s3.GetObjectOutput{
+				Body:         io.NopCloser(strings.NewReader("23456789")),
+				ContentRange: aws.String("bytes 2-9/10"),
+			},
+			expectedRange: "bytes=2-11",
+			expectedData:  "23456789",
+			expectedErr:   io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockS3Client{getObjectOutput: tt.output}
+			client := &Client{s3Client: mock}
+			p := make([]byte, tt.length)
+			n, err := client.NewObjectReader(context.Background(), "test-bucket", "test-key").ReadAt(p, 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedRange, aws.ToString(mock.getObjectInput.Range))
+			assert.Equal(t, tt.expectedData, string(p[:n]))
+		})
+	}
+}
+
+// TestParseContentRange tests parsing Content-Range header values
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedStart int64
+		expectedSize  int64
+		expectedOK    bool
+	}{
+		{name: "正常系: 範囲とサイズ", input: "bytes 100-199/1000", expectedStart: 100, expectedSize: 1000, expectedOK: true},
+		{name: "正常系: サイズ不明", input: "bytes 0-99/*", expectedStart: 0, expectedSize: -1, expectedOK: true},
+		{name: "異常系: 単位が不正", input: "items 0-99/1000"},
+		{name: "異常系: 範囲なし", input: "bytes */1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, size, ok := parseContentRange(tt.input)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedStart, start)
+			assert.Equal(t, tt.expectedSize, size)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectVersion is a version of an object or a delete marker.
+type ObjectVersion struct {
+	Key            string
+	VersionID      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	// Size, ETag and StorageClass are empty for delete markers.
+	Size         int64
+	ETag         string
+	StorageClass string
+	LastModified time.Time
+}
+
+// VersionsPage contains a page of versions and delete markers, sorted by key and then from the newest.
+// NextKeyMarker and NextVersionIDMarker continue the listing when Truncated is true.
+type VersionsPage struct {
+	Versions            []ObjectVersion
+	Truncated           bool
+	NextKeyMarker       string
+	NextVersionIDMarker string
+}
+
+// ListObjectVersions lists a page of the versions and delete markers of the objects with the specified prefix,
+// starting after keyMarker and versionIDMarker, which are empty for the first page.
+// Unlike ListObjects, the result is not cached since it is only needed to look into the history of objects.
+func (c *Client) ListObjectVersions(ctx context.Context, bucket, prefix, keyMarker, versionIDMarker string) (*VersionsPage, error) {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if c.PageSize > 0 {
+		input.MaxKeys = aws.Int32(c.PageSize)
+	}
+	if keyMarker != "" {
+		input.KeyMarker = aws.String(keyMarker)
+		if versionIDMarker != "" {
+			input.VersionIdMarker = aws.String(versionIDMarker)
+		}
+	}
+
+	output, err := c.s3Client.ListObjectVersions(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("ListObjectVersions failed for bucket %q prefix %q: %w", bucket, prefix, err)
+	}
+
+	versions := make([]ObjectVersion, 0, len(output.Versions)+len(output.DeleteMarkers))
+	for _, version := range output.Versions {
+		versions = append(versions, ObjectVersion{
+			Key:          aws.ToString(version.Key),
+			VersionID:    aws.ToString(version.VersionId),
+			IsLatest:     aws.ToBool(version.IsLatest),
+			Size:         aws.ToInt64(version.Size),
+			ETag:         aws.ToString(version.ETag),
+			StorageClass: string(version.StorageClass),
+			LastModified: aws.ToTime(version.LastModified),
+		})
+	}
+	for _, marker := range output.DeleteMarkers {
+		versions = append(versions, ObjectVersion{
+			Key:            aws.ToString(marker.Key),
+			VersionID:      aws.ToString(marker.VersionId),
+			IsLatest:       aws.ToBool(marker.IsLatest),
+			IsDeleteMarker: true,
+			LastModified:   aws.ToTime(marker.LastModified),
+		})
+	}
+	// S3 returns the versions and the delete markers in separate lists, so merge them into the history of each key
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Key != versions[j].Key {
+			return versions[i].Key < versions[j].Key
+		}
+		if versions[i].IsLatest != versions[j].IsLatest {
+			return versions[i].IsLatest
+		}
+		return versions[i].LastModified.After(versions[j].LastModified)
+	})
+
+	return &VersionsPage{
+		Versions:            versions,
+		Truncated:           aws.ToBool(output.IsTruncated),
+		NextKeyMarker:       aws.ToString(output.NextKeyMarker),
+		NextVersionIDMarker: aws.ToString(output.NextVersionIdMarker),
+	}, nil
+}
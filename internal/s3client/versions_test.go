@@ -0,0 +1,69 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_ListObjectVersions tests merging versions and delete markers into the history of each key
+func TestClient_ListObjectVersions(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+
+	mock := &MockS3Client{listVersionsPages: map[string]*s3.ListObjectVersionsOutput{
+		"": {
+			Versions: []types.ObjectVersion{
+				{Key: aws.String("a.txt"), VersionId: aws.String("a2"), IsLatest: aws.Bool(false), Size: aws.Int64(2), ETag: aws.String(`"e2"`), StorageClass: types.ObjectVersionStorageClassStandard, LastModified: &t2},
+				{Key: aws.String("a.txt"), VersionId: aws.String("a1"), IsLatest: aws.Bool(false), Size: aws.Int64(1), ETag: aws.String(`"e1"`), StorageClass: types.ObjectVersionStorageClassStandard, LastModified: &t1},
+				{Key: aws.String("b.txt"), VersionId: aws.String("b1"), IsLatest: aws.Bool(true), Size: aws.Int64(3), LastModified: &t1},
+			},
+			DeleteMarkers: []types.DeleteMarkerEntry{
+				{Key: aws.String("a.txt"), VersionId: aws.String("d1"), IsLatest: aws.Bool(true), LastModified: &t3},
+			},
+			IsTruncated:         aws.Bool(true),
+			NextKeyMarker:       aws.String("b.txt"),
+			NextVersionIdMarker: aws.String("b1"),
+		},
+		"b.txt": {
+			Versions: []types.ObjectVersion{
+				{Key: aws.String("c.txt"), VersionId: aws.String("null"), IsLatest: aws.Bool(true), Size: aws.Int64(4), LastModified: &t1},
+			},
+			IsTruncated: aws.Bool(false),
+		},
+	}}
+	client := &Client{s3Client: mock, PageSize: 4}
+
+	page, err := client.ListObjectVersions(context.Background(), "test-bucket", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), aws.ToInt32(mock.listVersionsInput.MaxKeys))
+	assert.Nil(t, mock.listVersionsInput.KeyMarker)
+	assert.Equal(t, &VersionsPage{
+		Versions: []ObjectVersion{
+			{Key: "a.txt", VersionID: "d1", IsLatest: true, IsDeleteMarker: true, LastModified: t3},
+			{Key: "a.txt", VersionID: "a2", Size: 2, ETag: `"e2"`, StorageClass: "STANDARD", LastModified: t2},
+			{Key: "a.txt", VersionID: "a1", Size: 1, ETag: `"e1"`, StorageClass: "STANDARD", LastModified: t1},
+			{Key: "b.txt", VersionID: "b1", IsLatest: true, Size: 3, LastModified: t1},
+		},
+		Truncated:           true,
+		NextKeyMarker:       "b.txt",
+		NextVersionIDMarker: "b1",
+	}, page)
+
+	page, err = client.ListObjectVersions(context.Background(), "test-bucket", "", page.NextKeyMarker, page.NextVersionIDMarker)
+	assert.NoError(t, err)
+	assert.Equal(t, "b1", aws.ToString(mock.listVersionsInput.VersionIdMarker))
+	assert.Equal(t, []ObjectVersion{{Key: "c.txt", VersionID: "null", IsLatest: true, Size: 4, LastModified: t1}}, page.Versions)
+	assert.False(t, page.Truncated)
+
+	mock.listVersionsError = errors.New("access denied")
+	_, err = client.ListObjectVersions(context.Background(), "test-bucket", "logs/", "", "")
+	assert.EqualError(t, err, `ListObjectVersions failed for bucket "test-bucket" prefix "logs/": access denied`)
+}
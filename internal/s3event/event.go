@@ -0,0 +1,79 @@
+package s3event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Notification is an S3 event notification message.
+// MinIO webhook targets send the same Records together with EventName and Key.
+type Notification struct {
+	EventName string   `json:"EventName"`
+	Key       string   `json:"Key"`
+	Records   []Record `json:"Records"`
+}
+
+// Record is a single event record of an S3 event notification.
+type Record struct {
+	EventName string `json:"eventName"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key string `json:"key"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// ObjectChange identifies an object that was created or removed.
+type ObjectChange struct {
+	Bucket string
+	Key    string
+}
+
+// Parse decodes an S3 event notification and returns the objects that were created or removed.
+// An empty body is treated as a notification without records.
+func Parse(r io.Reader) ([]ObjectChange, error) {
+	var notification Notification
+	if err := json.NewDecoder(r).Decode(&notification); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to decode S3 event notification: %w", err)
+	}
+
+	var changes []ObjectChange
+	for _, record := range notification.Records {
+		if !isObjectChange(record.EventName) {
+			continue
+		}
+		// Object keys are URL-encoded in event notifications
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unescape object key %q: %w", record.S3.Object.Key, err)
+		}
+		changes = append(changes, ObjectChange{Bucket: record.S3.Bucket.Name, Key: key})
+	}
+
+	// MinIO webhook targets may omit Records and only set EventName and Key ("bucket/key"), which is not URL-encoded
+	if len(notification.Records) == 0 && isObjectChange(notification.EventName) {
+		bucket, key, found := strings.Cut(notification.Key, "/")
+		if found {
+			changes = append(changes, ObjectChange{Bucket: bucket, Key: key})
+		}
+	}
+
+	return changes, nil
+}
+
+// isObjectChange reports whether the event name is an ObjectCreated or ObjectRemoved event.
+// Both the AWS ("ObjectCreated:Put") and MinIO ("s3:ObjectCreated:Put") forms are accepted.
+func isObjectChange(eventName string) bool {
+	eventName = strings.TrimPrefix(eventName, "s3:")
+	return strings.HasPrefix(eventName, "ObjectCreated:") || strings.HasPrefix(eventName, "ObjectRemoved:")
+}
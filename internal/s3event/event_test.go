@@ -0,0 +1,92 @@
+package s3event
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParse tests the Parse function with various notification bodies.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expected    []ObjectChange
+		expectedErr string
+	}{
+		{
+			name: "AWSの形式",
+			body: `{"Records":[
+				{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket1"},"object":{"key":"dir/my+file%281%29.txt"}}},
+				{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket2"},"object":{"key":"a.txt"}}},
+				{"eventName":"ObjectRestore:Completed","s3":{"bucket":{"name":"bucket3"},"object":{"key":"b.txt"}}}
+			]}`,
+			expected: []ObjectChange{
+				{Bucket: "bucket1", Key: "dir/my file(1).txt"},
+				{Bucket: "bucket2", Key: "a.txt"},
+			},
+		},
+		{
+			name: "MinIOのwebhook形式",
+			body: `{"EventName":"s3:ObjectCreated:Put","Key":"bucket1/dir/a.txt","Records":[
+				{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"bucket1"},"object":{"key":"dir%2Fa.txt"}}}
+			]}`,
+			expected: []ObjectChange{
+				{Bucket: "bucket1", Key: "dir/a.txt"},
+			},
+		},
+		{
+			name: "MinIOのRecordsなしの形式",
+			body: `{"EventName":"s3:ObjectRemoved:Delete","Key":"bucket1/dir/a.txt"}`,
+			expected: []ObjectChange{
+				{Bucket: "bucket1", Key: "dir/a.txt"},
+			},
+		},
+		{
+			name: "MinIOのトップレベルのKeyはエスケープされていない",
+			body: `{"EventName":"s3:ObjectCreated:Put","Key":"bucket1/dir/a+b%20c.txt","Records":[
+				{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"bucket1"},"object":{"key":"dir%2Fa%2Bb%2520c.txt"}}}
+			]}`,
+			expected: []ObjectChange{
+				{Bucket: "bucket1", Key: "dir/a+b%20c.txt"},
+			},
+		},
+		{
+			name: "MinIOのRecordsなしの形式で+を含むキー",
+			body: `{"EventName":"s3:ObjectRemoved:Delete","Key":"bucket1/dir/a+b%20c.txt"}`,
+			expected: []ObjectChange{
+				{Bucket: "bucket1", Key: "dir/a+b%20c.txt"},
+			},
+		},
+		{
+			name:     "空のボディ",
+			body:     "",
+			expected: nil,
+		},
+		{
+			name:        "不正なJSON",
+			body:        "{",
+			expectedErr: "failed to decode S3 event notification",
+		},
+		{
+			name:        "不正なキーのエスケープ",
+			body:        `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket1"},"object":{"key":"%zz"}}}]}`,
+			expectedErr: "failed to unescape object key \"%zz\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(strings.NewReader(tt.body))
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// CacheStatus is the response of the admin cache endpoint.
+type CacheStatus struct {
+	Entries []s3client.CacheEntryInfo `json:"entries"`
+	Metrics s3client.Metrics          `json:"metrics"`
+}
+
+// setupAdminRoutes sets up the admin routes authenticated by the bearer token.
+// The routes are not registered when the token is empty.
+func setupAdminRoutes(e *echo.Echo, client *s3client.Client, token string) {
+	if token == "" {
+		return
+	}
+
+	admin := e.Group("/-/admin", bearerAuth(token))
+
+	// List the cache entries and the hit/miss counters
+	admin.GET("/cache", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		return c.JSON(http.StatusOK, CacheStatus{
+			Entries: client.ListObjectsCacheEntries(ctx),
+			Metrics: client.Metrics(),
+		})
+	})
+
+	// Show the client metrics
+	admin.GET("/metrics", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, client.Metrics())
+	})
+
+	// Purge the cache of a prefix subtree, a bucket, or entirely
+	admin.DELETE("/cache", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		bucket := c.QueryParam("bucket")
+		prefix := c.QueryParam("prefix")
+
+		switch {
+		case bucket != "":
+			client.ClearListObjectsCacheTree(ctx, bucket, prefix)
+		case prefix != "":
+			return echo.NewHTTPError(http.StatusBadRequest, "prefix requires bucket")
+		default:
+			client.ClearAllCache(ctx)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+// bearerAuth authenticates requests by the bearer token in the Authorization header.
+// Requests without the token are also answered with 401 Unauthorized, unlike middleware.KeyAuth.
+func bearerAuth(token string) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1, nil
+		},
+		ErrorHandler: func(err error, c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAdminRoutes tests the authentication of the admin routes and inspecting and purging the cache
+func TestAdminRoutes(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, map[string]stubObject{
+		"bucket1/dir/a.txt": {data: "a"},
+		"bucket2/b.txt":     {data: "b"},
+	})
+	e := newTestEcho(t)
+	setupAdminRoutes(e, client, "secret")
+
+	// Fill the cache with a listing of each bucket
+	for _, bucket := range []string{"bucket1", "bucket2"} {
+		_, _, err := client.ListObjects(ctx, bucket, "", "", "")
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "異常系: トークンなし", method: http.MethodGet, target: "/-/admin/cache", expectedStatus: http.StatusUnauthorized},
+		{name: "異常系: トークンが不正", method: http.MethodGet, target: "/-/admin/cache", authorization: "Bearer wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "異常系: スキームが不正", method: http.MethodGet, target: "/-/admin/cache", authorization: "Basic secret", expectedStatus: http.StatusUnauthorized},
+		{name: "異常系: バケットなしのプレフィックス", method: http.MethodDelete, target: "/-/admin/cache?prefix=dir/", authorization: "Bearer secret", expectedStatus: http.StatusBadRequest},
+		{name: "正常系: キャッシュの一覧", method: http.MethodGet, target: "/-/admin/cache", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := serve(e, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// The listing of each bucket is cached
+	req := httptest.NewRequest(http.MethodGet, "/-/admin/cache", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	var status CacheStatus
+	assert.NoError(t, json.Unmarshal(serve(e, req).Body.Bytes(), &status))
+	assert.Len(t, status.Entries, 2)
+
+	// Purging a bucket keeps the other bucket
+	req = httptest.NewRequest(http.MethodDelete, "/-/admin/cache?bucket=bucket1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	assert.Equal(t, http.StatusNoContent, serve(e, req).Code)
+	entries := client.ListObjectsCacheEntries(ctx)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "bucket2/?token=", entries[0].Key)
+
+	// Purging without a bucket clears everything
+	req = httptest.NewRequest(http.MethodDelete, "/-/admin/cache", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	assert.Equal(t, http.StatusNoContent, serve(e, req).Code)
+	assert.Empty(t, client.ListObjectsCacheEntries(ctx))
+}
+
+// TestAdminRoutes_NoToken tests that the admin routes are not registered without a token
+func TestAdminRoutes_NoToken(t *testing.T) {
+	e := newTestEcho(t)
+	setupAdminRoutes(e, newTestClient(t, nil), "")
+
+	req := httptest.NewRequest(http.MethodGet, "/-/admin/cache", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	assert.Equal(t, http.StatusNotFound, serve(e, req).Code)
+}
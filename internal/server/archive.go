@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/korosuke613/polybuckets/internal"
+	"github.com/korosuke613/polybuckets/internal/archive"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// archiveManifestName is the name of the file listing the objects that could not be added to an archive.
+const archiveManifestName = "FAILED_OBJECTS.txt"
+
+// handleArchive streams all objects under a prefix as a ZIP or tar.gz archive without buffering to disk.
+// The number of objects and their total size are limited to protect the server.
+func handleArchive(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the folder
+	folder, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	folder = strings.Trim(folder, "/")
+
+	renderError := func(status int, err error) error {
+		return c.Render(status, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   folder,
+		})
+	}
+
+	format, err := archive.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return renderError(http.StatusBadRequest, err)
+	}
+
+	prefix := folderPrefix(folder)
+
+	// List the objects first so that the limits are checked before the response starts
+	objects, err := client.ListAllObjects(ctx, bucket, prefix, env.PBConfig.ArchiveMaxObjects)
+	if err != nil {
+		if errors.Is(err, s3client.ErrTooManyObjects) {
+			return renderError(http.StatusBadRequest, err)
+		}
+		return renderError(http.StatusInternalServerError, err)
+	}
+	var totalSize int64
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		totalSize += obj.Size
+		keys[i] = obj.Key
+	}
+	if maxBytes := env.PBConfig.ArchiveMaxBytes; maxBytes > 0 && totalSize > maxBytes {
+		return renderError(http.StatusBadRequest, fmt.Errorf("total size of objects under %q exceeds the limit of %d bytes", prefix, maxBytes))
+	}
+
+	return streamArchive(c, client, format, bucket, folder, keys)
+}
+
+// handleArchiveSelection streams the objects selected in objects.html as a ZIP or tar.gz archive.
+// The selection is posted as `key` form values together with the `prefix` of the listing.
+func handleArchiveSelection(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	bucket := c.Param("bucket")
+	folder := strings.Trim(c.FormValue("prefix"), "/")
+
+	renderError := func(status int, err error) error {
+		return c.Render(status, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   folder,
+		})
+	}
+
+	format, err := archive.ParseFormat(c.FormValue("format"))
+	if err != nil {
+		return renderError(http.StatusBadRequest, err)
+	}
+
+	params, err := c.FormParams()
+	if err != nil {
+		return renderError(http.StatusBadRequest, err)
+	}
+	keys := params["key"]
+	if len(keys) == 0 {
+		return renderError(http.StatusBadRequest, errors.New("no objects are selected"))
+	}
+	if maxObjects := env.PBConfig.ArchiveMaxObjects; maxObjects > 0 && len(keys) > maxObjects {
+		return renderError(http.StatusBadRequest, fmt.Errorf("%d objects are selected, exceeding the limit of %d objects", len(keys), maxObjects))
+	}
+
+	return streamArchive(c, client, format, bucket, folder, keys)
+}
+
+// folderPrefix returns the S3 prefix of the folder, with a trailing slash unless it is the bucket root.
+func folderPrefix(folder string) string {
+	if folder == "" {
+		return ""
+	}
+	return folder + "/"
+}
+
+// streamArchive writes the objects to the response as an archive named after the folder, or the bucket at the root.
+// Objects that cannot be fetched or would exceed the size limit are skipped and listed in a manifest file.
+func streamArchive(c echo.Context, client *s3client.Client, format archive.Format, bucket, folder string, keys []string) error {
+	ctx := c.Request().Context()
+	prefix := folderPrefix(folder)
+
+	name := bucket
+	if base := path.Base(folder); folder != "" && base != "." && base != ".." {
+		name = base
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, format.ContentType())
+	header.Set(echo.HeaderContentDisposition, internal.ContentDisposition("attachment", name+format.Extension()))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := archive.NewWriter(format, c.Response())
+	var manifest bytes.Buffer
+	remaining := env.PBConfig.ArchiveMaxBytes
+	for _, key := range keys {
+		// Keys are posted by users and S3 keys may contain "..", so entries outside of the folder are skipped
+		entryName, ok := archive.EntryName(name, strings.TrimPrefix(key, prefix))
+		if !ok {
+			fmt.Fprintf(&manifest, "%s: skipped since the key would be extracted outside of the folder\n", key)
+			continue
+		}
+
+		result, err := client.GetObject(ctx, bucket, key)
+		if err != nil {
+			fmt.Fprintf(&manifest, "%s: %v\n", key, err)
+			continue
+		}
+
+		size := aws.ToInt64(result.ContentLength)
+		if env.PBConfig.ArchiveMaxBytes > 0 {
+			if size > remaining {
+				result.Body.Close()
+				fmt.Fprintf(&manifest, "%s: skipped since the archive would exceed the limit of %d bytes\n", key, env.PBConfig.ArchiveMaxBytes)
+				continue
+			}
+			remaining -= size
+		}
+
+		err = w.Add(entryName, size, aws.ToTime(result.LastModified), result.Body)
+		result.Body.Close()
+		if err != nil {
+			// The archive is broken in the middle of an entry, so it cannot be continued
+			return err
+		}
+	}
+
+	if manifest.Len() > 0 {
+		slog.Warn("some objects were not added to the archive", "bucket", bucket, "prefix", prefix, "manifest", manifest.String())
+		if err := w.Add(path.Join(name, archiveManifestName), int64(manifest.Len()), time.Now(), &manifest); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
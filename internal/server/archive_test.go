@@ -0,0 +1,217 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// readZipFiles returns the contents of the files in the ZIP archive by their names
+func readZipFiles(t *testing.T, data []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+// TestHandleArchive tests the folder download, including the manifest of the objects that were not added
+func TestHandleArchive(t *testing.T) {
+	client := newTestClient(t, map[string]stubObject{
+		"bucket1/dir/a.txt":         {data: "aaa", etag: `"a"`},
+		"bucket1/dir/sub/b.txt":     {data: "bbbb", etag: `"b"`},
+		"bucket1/dir/denied.txt":    {data: "denied", etag: `"denied"`, err: statusError(http.StatusForbidden)},
+		"bucket1/dir/../escape.txt": {data: "escape", etag: `"escape"`},
+		"bucket1/other.txt":         {data: "other", etag: `"other"`},
+	})
+	e := newTestEcho(t)
+	e.GET("/-/archive/:bucket/*", func(c echo.Context) error {
+		return handleArchive(c, client)
+	})
+
+	tests := []struct {
+		name                string
+		target              string
+		maxObjects          int
+		maxBytes            int64
+		expectedStatus      int
+		expectedDisposition string
+		expectedFiles       map[string]string
+		expectedManifest    []string
+	}{
+		{
+			name:                "正常系: フォルダー配下をZIPでダウンロード",
+			target:              "/-/archive/bucket1/dir",
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="dir.zip"; filename*=UTF-8''dir.zip`,
+			expectedFiles:       map[string]string{"dir/a.txt": "aaa", "dir/sub/b.txt": "bbbb"},
+			expectedManifest: []string{
+				"dir/../escape.txt: skipped since the key would be extracted outside of the folder",
+				"dir/denied.txt: ",
+			},
+		},
+		{
+			name:                "正常系: バケットのルートはバケット名",
+			target:              "/-/archive/bucket1/",
+			maxBytes:            100,
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="bucket1.zip"; filename*=UTF-8''bucket1.zip`,
+			expectedFiles:       map[string]string{"bucket1/dir/a.txt": "aaa", "bucket1/dir/sub/b.txt": "bbbb", "bucket1/other.txt": "other"},
+			expectedManifest: []string{
+				"dir/../escape.txt: skipped since the key would be extracted outside of the folder",
+				"dir/denied.txt: ",
+			},
+		},
+		{
+			name:           "異常系: オブジェクト数の上限を超える",
+			target:         "/-/archive/bucket1/dir",
+			maxObjects:     2,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系: 合計サイズの上限を超える",
+			target:         "/-/archive/bucket1/dir",
+			maxBytes:       10,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系: 不明な形式",
+			target:         "/-/archive/bucket1/dir?format=rar",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(config *env.PBConfigType) {
+				config.ArchiveMaxObjects = tt.maxObjects
+				config.ArchiveMaxBytes = tt.maxBytes
+			})
+
+			rec := serve(e, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, tt.expectedDisposition, rec.Header().Get(echo.HeaderContentDisposition))
+
+			// The manifest is written last in the root folder of the archive
+			files := readZipFiles(t, rec.Body.Bytes())
+			var manifest string
+			for name, content := range files {
+				if path.Base(name) == archiveManifestName {
+					manifest = content
+					delete(files, name)
+				}
+			}
+			assert.Equal(t, tt.expectedFiles, files)
+			assert.Equal(t, len(tt.expectedManifest), strings.Count(manifest, "\n"))
+			for _, line := range tt.expectedManifest {
+				assert.Contains(t, manifest, line)
+			}
+		})
+	}
+}
+
+// TestHandleArchiveSelection tests the download of selected objects, including the manifest of the objects that were not added
+func TestHandleArchiveSelection(t *testing.T) {
+	client := newTestClient(t, map[string]stubObject{
+		"bucket1/dir/a.txt":      {data: "aaa", etag: `"a"`},
+		"bucket1/dir/sub/b.txt":  {data: "bbbb", etag: `"b"`},
+		"bucket1/dir/denied.txt": {data: "denied", etag: `"denied"`, err: statusError(http.StatusForbidden)},
+	})
+	e := newTestEcho(t)
+	e.POST("/-/archive/:bucket", func(c echo.Context) error {
+		return handleArchiveSelection(c, client)
+	})
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		maxObjects       int
+		expectedStatus   int
+		expectedFiles    map[string]string
+		expectedManifest []string
+	}{
+		{
+			name:           "正常系: 選択したオブジェクトをZIPでダウンロード",
+			form:           url.Values{"prefix": {"dir/"}, "key": {"dir/a.txt", "dir/sub/b.txt"}},
+			expectedStatus: http.StatusOK,
+			expectedFiles:  map[string]string{"dir/a.txt": "aaa", "dir/sub/b.txt": "bbbb"},
+		},
+		{
+			name:           "正常系: 失敗したオブジェクトはマニフェストに記載",
+			form:           url.Values{"prefix": {"dir"}, "key": {"dir/a.txt", "dir/missing.txt", "dir/denied.txt", "dir/../other.txt"}},
+			expectedStatus: http.StatusOK,
+			expectedFiles:  map[string]string{"dir/a.txt": "aaa"},
+			expectedManifest: []string{
+				"dir/missing.txt: ",
+				"dir/denied.txt: ",
+				"dir/../other.txt: skipped since the key would be extracted outside of the folder",
+			},
+		},
+		{
+			name:           "異常系: 選択なし",
+			form:           url.Values{"prefix": {"dir/"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "異常系: オブジェクト数の上限を超える",
+			form:           url.Values{"prefix": {"dir/"}, "key": {"dir/a.txt", "dir/sub/b.txt"}},
+			maxObjects:     1,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(config *env.PBConfigType) {
+				config.ArchiveMaxObjects = tt.maxObjects
+				config.ArchiveMaxBytes = 0
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/-/archive/bucket1", strings.NewReader(tt.form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			rec := serve(e, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			files := readZipFiles(t, rec.Body.Bytes())
+			manifest := files["dir/"+archiveManifestName]
+			delete(files, "dir/"+archiveManifestName)
+			assert.Equal(t, tt.expectedFiles, files)
+			assert.Equal(t, len(tt.expectedManifest), strings.Count(manifest, "\n"))
+			for _, line := range tt.expectedManifest {
+				assert.Contains(t, manifest, line)
+			}
+		})
+	}
+}
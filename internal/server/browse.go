@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/korosuke613/polybuckets/internal"
+	"github.com/korosuke613/polybuckets/internal/archive"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/lru"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxArchiveEntries is the maximum number of entries listed from a tar archive.
+	maxArchiveEntries = 10000
+	// maxArchiveReadBytes is the maximum number of bytes of a tar archive read to list it or to find a member.
+	maxArchiveReadBytes = 1 << 30
+	// tarEntriesCacheMaxBytes is the maximum total size of the entries kept by tarEntriesCache.
+	tarEntriesCacheMaxBytes = 16 << 20
+)
+
+// tarEntriesCache keeps the entries of tar archives with the ETag of the object they were read from,
+// so that browsing the folders of an archive does not read the whole archive again.
+type tarEntriesCache = lru.Cache[tarEntries]
+
+// tarEntries is the list of entries of the version of a tar archive with the ETag.
+type tarEntries struct {
+	etag      string
+	entries   []archive.Entry
+	truncated bool
+}
+
+// newTarEntriesCache creates an empty tarEntriesCache bounded by tarEntriesCacheMaxBytes.
+func newTarEntriesCache() *tarEntriesCache {
+	return lru.New(0, tarEntriesCacheMaxBytes, func(tar tarEntries) int64 {
+		// Approximate size of an entry besides its name
+		const entryOverhead = 64
+		size := int64(len(tar.etag))
+		for _, entry := range tar.entries {
+			size += int64(len(entry.Name)) + entryOverhead
+		}
+		return size
+	})
+}
+
+// handleBrowse lists the folder given by the path query parameter inside a ZIP or tar archive in objects.html.
+// ZIP archives are listed from their central directory with Range requests; tar archives are read as a stream
+// and their entries are cached.
+func handleBrowse(c echo.Context, client *s3client.Client, tars *tarEntriesCache) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	prefix := strings.TrimSuffix(path.Dir(key), ".")
+
+	dir := strings.Trim(c.QueryParam("path"), "/")
+	parentPath := ""
+	if dir != "" {
+		parentPath = strings.TrimSuffix(path.Dir(dir), ".")
+		if parentPath != "" {
+			parentPath += "/"
+		}
+		dir += "/"
+	}
+
+	var entries []archive.Entry
+	truncated := false
+	switch {
+	case archive.IsZip(key):
+		var size int64
+		if size, err = objectSize(ctx, client, bucket, key); err == nil {
+			entries, err = archive.ReadZipEntries(client.NewObjectReader(ctx, bucket, key), size)
+		}
+	case archive.IsTar(key):
+		entries, truncated, err = readTarEntries(ctx, client, tars, bucket, key)
+	default:
+		err = fmt.Errorf("%q is not a ZIP or tar archive", key)
+	}
+	if err != nil {
+		return c.Render(archiveErrorStatus(err), "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   prefix,
+		})
+	}
+
+	var objects []s3client.ObjectInfo
+	for _, entry := range archive.ListDir(entries, dir) {
+		objects = append(objects, s3client.ObjectInfo{
+			Name:         entry.Name,
+			ShortName:    strings.TrimPrefix(entry.Name, dir),
+			IsDirectory:  entry.IsDir,
+			Size:         s3client.FormatSize(entry.Size),
+			LastModified: entry.ModTime,
+		})
+	}
+
+	return c.Render(http.StatusOK, "objects.html", map[string]interface{}{
+		"SiteName":    siteName,
+		"Bucket":      bucket,
+		"Prefix":      prefix,
+		"Archive":     key,
+		"ArchivePath": dir,
+		"ParentPath":  parentPath,
+		"Objects":     listingItems(objects),
+		"Truncated":   truncated,
+		"MaxEntries":  maxArchiveEntries,
+		"MaxSize":     s3client.FormatSize(maxArchiveReadBytes),
+	})
+}
+
+// readTarEntries lists a tar archive, or returns the cached entries if the archive has not changed since.
+func readTarEntries(ctx context.Context, client *s3client.Client, tars *tarEntriesCache, bucket, key string) ([]archive.Entry, bool, error) {
+	cacheKey := bucket + "/" + key
+	cached, found := tars.Get(cacheKey)
+	var opts []s3client.GetObjectOption
+	if found {
+		opts = append(opts, s3client.WithIfNoneMatch(cached.etag))
+	}
+
+	result, err := client.GetObject(ctx, bucket, key, opts...)
+	if err != nil {
+		if found && httpStatusCode(err) == http.StatusNotModified {
+			return cached.entries, cached.truncated, nil
+		}
+		return nil, false, err
+	}
+	defer result.Body.Close()
+
+	entries, truncated, err := archive.ReadTarEntries(result.Body, maxArchiveEntries, maxArchiveReadBytes)
+	if err != nil {
+		return nil, false, err
+	}
+	if etag := aws.ToString(result.ETag); etag != "" {
+		tars.Set(cacheKey, tarEntries{etag: etag, entries: entries, truncated: truncated})
+	}
+	return entries, truncated, nil
+}
+
+// handleExtract streams the file given by the name query parameter out of a ZIP or tar archive.
+// The data of a ZIP member is read with a single Range request; a tar archive is read as a stream up to the member.
+// Content-Length is only sent for tar members, whose size is enforced by the tar format, since the size of
+// a ZIP member comes from the central directory. The response is aborted if the data does not match it.
+func handleExtract(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	name := strings.TrimPrefix(c.QueryParam("name"), "/")
+	if name == "" {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    "no member name is specified",
+		})
+	}
+
+	var entry *archive.Entry
+	var body io.ReadCloser
+	switch {
+	case archive.IsZip(key):
+		entry, body, err = openZipMember(ctx, client, bucket, key, name)
+	case archive.IsTar(key):
+		entry, body, err = openTarMember(ctx, client, bucket, key, name)
+	default:
+		err = fmt.Errorf("%q is not a ZIP or tar archive", key)
+	}
+	if err != nil {
+		return c.Render(archiveErrorStatus(err), "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   strings.TrimSuffix(path.Dir(key), "."),
+		})
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	if archive.IsTar(key) {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(entry.Size, 10))
+	}
+	header.Set(echo.HeaderLastModified, entry.ModTime.UTC().Format(http.TimeFormat))
+	header.Set(echo.HeaderContentDisposition, internal.ContentDisposition("attachment", path.Base(name)))
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+	if err := c.Stream(http.StatusOK, contentType, body); err != nil {
+		if c.Response().Committed {
+			// Abort the response, so that the client does not take a corrupted member as complete
+			slog.Warn("failed to extract archive member", "bucket", bucket, "key", key, "name", name, "error", err)
+			panic(http.ErrAbortHandler)
+		}
+		return err
+	}
+	return nil
+}
+
+// memberReader reads a member of an archive and closes the body of the archive object.
+type memberReader struct {
+	io.Reader
+	io.Closer
+}
+
+// openZipMember finds the member of a ZIP archive and opens its data with a Range request.
+func openZipMember(ctx context.Context, client *s3client.Client, bucket, key, name string) (*archive.Entry, io.ReadCloser, error) {
+	size, err := objectSize(ctx, client, bucket, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := archive.FindZipMember(client.NewObjectReader(ctx, bucket, key), size, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A Range request cannot be empty
+	if member.CompressedSize == 0 {
+		return &member.Entry, io.NopCloser(member.Reader(strings.NewReader(""))), nil
+	}
+	byteRange := fmt.Sprintf("bytes=%d-%d", member.Offset, member.Offset+member.CompressedSize-1)
+	result, err := client.GetObject(ctx, bucket, key, s3client.WithRange(byteRange))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &member.Entry, memberReader{Reader: member.Reader(result.Body), Closer: result.Body}, nil
+}
+
+// openTarMember reads a tar archive as a stream up to the member and returns the reader of its content.
+func openTarMember(ctx context.Context, client *s3client.Client, bucket, key, name string) (*archive.Entry, io.ReadCloser, error) {
+	body, err := getObjectBody(ctx, client, bucket, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry, r, err := archive.FindTarMember(body, name, maxArchiveReadBytes)
+	if err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+	return entry, memberReader{Reader: r, Closer: body}, nil
+}
+
+// objectSize returns the size of an object with a Range request of its first byte.
+func objectSize(ctx context.Context, client *s3client.Client, bucket, key string) (int64, error) {
+	chunk, err := client.ReadObjectRange(ctx, bucket, key, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+	return chunk.Size, nil
+}
+
+// getObjectBody returns the body of a whole object.
+func getObjectBody(ctx context.Context, client *s3client.Client, bucket, key string) (io.ReadCloser, error) {
+	result, err := client.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+// archiveErrorStatus returns the HTTP status code for an error reading an archive.
+func archiveErrorStatus(err error) int {
+	if errors.Is(err, archive.ErrNotFound) || httpStatusCode(err) == http.StatusNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
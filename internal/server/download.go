@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/korosuke613/polybuckets/internal"
+	"github.com/korosuke613/polybuckets/internal/decompress"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// handleDownload streams an object from S3 with the object's headers,
+// or redirects to a presigned URL if enabled for the bucket.
+// Range and If-Range requests are passed through to S3 and answered with 206 Partial Content.
+// If-None-Match and If-Modified-Since are passed through to S3 and answered with 304 Not Modified.
+// With the decompress query parameter set to true, the decompressed content of a compressed object is sent instead.
+// The versionId query parameter downloads a specific version of the object instead of the latest one.
+func handleDownload(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+	key := c.Param("*")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(key)
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+
+	version := s3client.WithVersionID(c.QueryParam("versionId"))
+	if c.QueryParam("decompress") == "true" {
+		return downloadDecompressed(c, client, bucket, key, version)
+	}
+
+	// Redirect to a presigned URL instead of proxying if enabled for the bucket
+	if env.PBConfig.PresignEnabledFor(bucket) && client.CanPresign() {
+		presignedURL, err := client.PresignGetObject(ctx, bucket, key, env.PBConfig.PresignExpiry,
+			s3client.WithResponseContentDisposition(internal.ContentDisposition("attachment", path.Base(key))),
+			s3client.WithResponseCacheControl(env.PBConfig.CacheControlFor(bucket)),
+			version,
+		)
+		if err == nil {
+			// The redirect must not be cached longer than the presigned URL is valid
+			c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+			return c.Redirect(http.StatusFound, presignedURL)
+		}
+		slog.Warn("failed to presign download, falling back to proxying", "bucket", bucket, "key", key, "error", err)
+	}
+
+	header := c.Response().Header()
+	if cacheControl := env.PBConfig.CacheControlFor(bucket); cacheControl != "" {
+		header.Set(echo.HeaderCacheControl, cacheControl)
+	}
+
+	// Get the object (or the requested range of it) from S3
+	reqHeader := c.Request().Header
+	byteRange := reqHeader.Get("Range")
+	conditions := append(conditionalOptions(reqHeader), version)
+	result, err := client.GetObject(ctx, bucket, key, append(conditions, s3client.WithRange(byteRange))...)
+	if err == nil && byteRange != "" && !ifRangeMatches(reqHeader.Get("If-Range"), result) {
+		// The object has changed since the client got the validator, so send the whole object
+		result.Body.Close()
+		result, err = client.GetObject(ctx, bucket, key, conditions...)
+	}
+	if err != nil {
+		switch httpStatusCode(err) {
+		case http.StatusNotModified:
+			// S3 does not return the validators with 304, so echo a single requested entity tag
+			if etag := reqHeader.Get("If-None-Match"); etag != "" && etag[0] == '"' && !strings.Contains(etag, ",") {
+				header.Set("ETag", etag)
+			}
+			return c.NoContent(http.StatusNotModified)
+		case http.StatusRequestedRangeNotSatisfiable:
+			// The response must have the current length of the object in Content-Range (RFC 9110)
+			size, err := client.ObjectSize(ctx, bucket, key, c.QueryParam("versionId"))
+			if err == nil {
+				header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			}
+			return c.NoContent(http.StatusRequestedRangeNotSatisfiable)
+		}
+		return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	defer result.Body.Close()
+
+	status := http.StatusOK
+	setObjectHeaders(header, result, path.Base(key))
+	header.Set("Accept-Ranges", "bytes")
+	if result.ContentRange != nil {
+		header.Set("Content-Range", *result.ContentRange)
+		status = http.StatusPartialContent
+	}
+	return c.Stream(status, contentType(result.ContentType), result.Body)
+}
+
+// downloadDecompressed streams the decompressed content of a gzip, zstd or bzip2 compressed object.
+// The compression is detected from the magic number; an object that is not compressed is sent as is.
+// Range and conditional requests are not supported since S3 knows neither the size nor the validators of the content.
+func downloadDecompressed(c echo.Context, client *s3client.Client, bucket, key string, version s3client.GetObjectOption) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+
+	result, err := client.GetObject(ctx, bucket, key, version)
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	defer result.Body.Close()
+
+	br := bufio.NewReader(result.Body)
+	head, _ := br.Peek(4)
+	var body io.Reader = br
+	filename := path.Base(key)
+	mimeType := contentType(result.ContentType)
+	if format := decompress.Detect(head); format != "" {
+		r, err := decompress.NewReader(format, br)
+		if err != nil {
+			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+				"SiteName": siteName,
+				"Error":    err.Error(),
+			})
+		}
+		defer r.Close()
+		body = r
+		filename = decompress.TrimExtension(filename)
+		mimeType = mime.TypeByExtension(path.Ext(filename))
+		if mimeType == "" {
+			mimeType = echo.MIMEOctetStream
+		}
+	}
+
+	header := c.Response().Header()
+	if cacheControl := env.PBConfig.CacheControlFor(bucket); cacheControl != "" {
+		header.Set(echo.HeaderCacheControl, cacheControl)
+	}
+	if result.LastModified != nil {
+		header.Set(echo.HeaderLastModified, result.LastModified.UTC().Format(http.TimeFormat))
+	}
+	header.Set(echo.HeaderContentDisposition, internal.ContentDisposition("attachment", filename))
+	return c.Stream(http.StatusOK, mimeType, body)
+}
+
+// conditionalOptions returns the GetObject options for the conditional request headers.
+func conditionalOptions(reqHeader http.Header) []s3client.GetObjectOption {
+	opts := []s3client.GetObjectOption{s3client.WithIfNoneMatch(reqHeader.Get("If-None-Match"))}
+	// If-Modified-Since is ignored when If-None-Match is present (RFC 9110)
+	if reqHeader.Get("If-None-Match") == "" {
+		if t, err := http.ParseTime(reqHeader.Get(echo.HeaderIfModifiedSince)); err == nil {
+			opts = append(opts, s3client.WithIfModifiedSince(t))
+		}
+	}
+	return opts
+}
+
+// httpStatusCode returns the HTTP status code of the S3 response that caused err, or 0 if unknown.
+func httpStatusCode(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// ifRangeMatches reports whether the If-Range validator matches the object, as described in RFC 9110.
+// An empty If-Range always matches.
+func ifRangeMatches(ifRange string, result *s3.GetObjectOutput) bool {
+	if ifRange == "" {
+		return true
+	}
+	// An entity tag is quoted; weak entity tags never match
+	if ifRange[0] == '"' {
+		return ifRange == aws.ToString(result.ETag)
+	}
+	if len(ifRange) > 2 && ifRange[:2] == "W/" {
+		return false
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil || result.LastModified == nil {
+		return false
+	}
+	return result.LastModified.Truncate(time.Second).Equal(t)
+}
+
+// setObjectHeaders copies the representation headers of the object to the response.
+func setObjectHeaders(header http.Header, result *s3.GetObjectOutput, filename string) {
+	if result.ContentLength != nil {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(*result.ContentLength, 10))
+	}
+	if result.ETag != nil {
+		header.Set("ETag", *result.ETag)
+	}
+	if result.LastModified != nil {
+		header.Set(echo.HeaderLastModified, result.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if result.ContentEncoding != nil {
+		header.Set(echo.HeaderContentEncoding, *result.ContentEncoding)
+	}
+	header.Set(echo.HeaderContentDisposition, internal.ContentDisposition("attachment", filename))
+}
+
+// contentType returns the object's content type, or application/octet-stream if it is unknown.
+func contentType(objectContentType *string) string {
+	if aws.ToString(objectContentType) == "" {
+		return echo.MIMEOctetStream
+	}
+	return *objectContentType
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHandleDownload tests the status, the headers and the body of downloads
+func TestHandleDownload(t *testing.T) {
+	client := newTestClient(t, map[string]stubObject{
+		"bucket1/dir/report 1.txt": {data: "0123456789", etag: `"etag1"`, contentType: "text/plain"},
+		"bucket1/data.bin":         {data: "binary", etag: `"etag2"`},
+	})
+	e := newTestEcho(t)
+	e.GET("/download/:bucket/*", func(c echo.Context) error {
+		return handleDownload(c, client)
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		header         map[string]string
+		expectedStatus int
+		expectedHeader map[string]string
+		expectedBody   string
+	}{
+		{
+			name:           "正常系: オブジェクトのヘッダー",
+			target:         "/download/bucket1/dir/report%201.txt",
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{
+				"Content-Type":        "text/plain",
+				"Content-Length":      "10",
+				"ETag":                `"etag1"`,
+				"Last-Modified":       "Sun, 26 Jan 2025 02:05:16 GMT",
+				"Content-Disposition": `attachment; filename="report 1.txt"; filename*=UTF-8''report%201.txt`,
+			},
+			expectedBody: "0123456789",
+		},
+		{
+			name:           "正常系: 不明なContent-Type",
+			target:         "/download/bucket1/data.bin",
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Content-Type": "application/octet-stream"},
+			expectedBody:   "binary",
+		},
+		{
+			name:           "正常系: 範囲リクエスト",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"Range": "bytes=2-5"},
+			expectedStatus: http.StatusPartialContent,
+			expectedHeader: map[string]string{"Content-Range": "bytes 2-5/10", "Content-Length": "4", "Accept-Ranges": "bytes"},
+			expectedBody:   "2345",
+		},
+		{
+			name:           "正常系: If-Rangeが一致",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"Range": "bytes=-3", "If-Range": `"etag1"`},
+			expectedStatus: http.StatusPartialContent,
+			expectedHeader: map[string]string{"Content-Range": "bytes 7-9/10"},
+			expectedBody:   "789",
+		},
+		{
+			name:           "正常系: If-Rangeが不一致なら全体",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"Range": "bytes=2-5", "If-Range": `"old"`},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Content-Range": "", "Content-Length": "10"},
+			expectedBody:   "0123456789",
+		},
+		{
+			name:           "異常系: 範囲外",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"Range": "bytes=10-"},
+			expectedStatus: http.StatusRequestedRangeNotSatisfiable,
+			expectedHeader: map[string]string{"Content-Range": "bytes */10"},
+		},
+		{
+			name:           "正常系: If-None-Matchが一致",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"If-None-Match": `"etag1"`},
+			expectedStatus: http.StatusNotModified,
+			expectedHeader: map[string]string{"ETag": `"etag1"`},
+		},
+		{
+			name:           "正常系: If-None-Matchが不一致",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"If-None-Match": `"old"`},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0123456789",
+		},
+		{
+			name:           "正常系: If-Modified-Sinceより新しくない",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"If-Modified-Since": "Sun, 26 Jan 2025 02:05:16 GMT"},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "正常系: If-Modified-SinceはIf-None-Matchがあれば無視",
+			target:         "/download/bucket1/dir/report%201.txt",
+			header:         map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": "Sun, 26 Jan 2025 02:05:16 GMT"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0123456789",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := serve(e, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			for key, value := range tt.expectedHeader {
+				assert.Equal(t, value, rec.Header().Get(key), key)
+			}
+			assert.Equal(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
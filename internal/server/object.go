@@ -0,0 +1,64 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// handleObjectDetail shows the metadata of an object returned by HeadObject and its tags in object.html.
+// Tags that cannot be read, e.g. without s3:GetObjectTagging, are replaced by a note so that the rest is still shown.
+func handleObjectDetail(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	prefix := strings.TrimSuffix(path.Dir(key), ".")
+
+	detail, err := client.HeadObject(ctx, bucket, key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if httpStatusCode(err) == http.StatusNotFound {
+			status = http.StatusNotFound
+		}
+		return c.Render(status, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   prefix,
+		})
+	}
+
+	tagsError := ""
+	tags, err := client.GetObjectTags(ctx, bucket, key)
+	if err != nil {
+		slog.Warn("failed to get object tags", "bucket", bucket, "key", key, "error", err)
+		tagsError = err.Error()
+	}
+
+	return c.Render(http.StatusOK, "object.html", map[string]interface{}{
+		"SiteName":  siteName,
+		"Bucket":    bucket,
+		"Prefix":    prefix,
+		"Key":       key,
+		"Name":      path.Base(key),
+		"Size":      s3client.FormatSize(detail.Size),
+		"Detail":    detail,
+		"Tags":      tags,
+		"TagsError": tagsError,
+	})
+}
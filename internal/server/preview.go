@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/korosuke613/polybuckets/internal/decompress"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/lru"
+	"github.com/korosuke613/polybuckets/internal/preview"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// handlePreview renders an object inline in preview.html.
+// Only the first PB_PREVIEW_MAX_BYTES bytes of the object are read with a Range request,
+// except for Parquet files whose footer and first rows are read with further Range requests.
+// Compressed objects are decompressed up to PB_PREVIEW_MAX_BYTES bytes.
+func handlePreview(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	prefix := strings.TrimSuffix(path.Dir(key), ".")
+
+	// For kinds that do not read the beginning of the object, only a byte is read to get the size
+	length := env.PBConfig.PreviewMaxBytes
+	readsHead := preview.KindByExtension(key).ReadsHead()
+	if !readsHead {
+		length = 1
+	}
+
+	chunk, err := client.ReadObjectRange(ctx, bucket, key, 0, length)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if httpStatusCode(err) == http.StatusNotFound {
+			status = http.StatusNotFound
+		}
+		return c.Render(status, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   prefix,
+		})
+	}
+
+	// Compressed objects are detected by their magic number and previewed decompressed
+	content, truncated := chunk.Data, chunk.Truncated()
+	compression := decompress.Detect(chunk.Data)
+	if readsHead && compression != "" {
+		content, truncated, err = decompress.Prefix(compression, chunk.Data, truncated, env.PBConfig.PreviewMaxBytes)
+		if err != nil {
+			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+				"SiteName": siteName,
+				"Error":    err.Error(),
+				"Bucket":   bucket,
+				"Prefix":   prefix,
+			})
+		}
+	}
+
+	data := map[string]interface{}{
+		"SiteName":    siteName,
+		"Bucket":      bucket,
+		"Prefix":      prefix,
+		"Key":         key,
+		"Name":        path.Base(key),
+		"Size":        s3client.FormatSize(chunk.Size),
+		"ShownSize":   s3client.FormatSize(int64(len(content))),
+		"Truncated":   truncated && readsHead,
+		"Compression": string(compression),
+	}
+
+	kind := preview.DetectKind(key, content)
+	// Markdown and tables can also be shown as the text they are written in
+	data["HasSource"] = (kind == preview.KindMarkdown || kind == preview.KindTable) && preview.IsText(content)
+	if data["HasSource"] == true && c.QueryParam("view") == "source" {
+		kind = preview.KindText
+	}
+	switch kind {
+	case preview.KindText:
+		data["Content"], err = preview.Highlight(decompress.TrimExtension(path.Base(key)), previewText(content))
+	case preview.KindMarkdown:
+		data["Content"], err = preview.RenderMarkdown(preview.TrimIncompleteRune(content), markdownLinkResolver(bucket, prefix))
+	case preview.KindTable:
+		data["Table"], err = preview.ParseTable(key, content, truncated, preview.DefaultTableRows)
+	case preview.KindParquet:
+		data["Parquet"], err = preview.ReadParquet(client.NewObjectReader(ctx, bucket, key), chunk.Size, preview.DefaultTableRows)
+	case preview.KindAvro:
+		var file *preview.AvroFile
+		file, err = preview.ReadAvro(content, truncated, preview.DefaultTableRows)
+		if err == nil {
+			data["Avro"] = file
+			data["Content"], err = preview.Highlight("schema.json", file.Schema)
+		}
+	}
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   prefix,
+		})
+	}
+	data["Kind"] = string(kind)
+
+	return c.Render(http.StatusOK, "preview.html", data)
+}
+
+// previewText converts the beginning of an object to a string, replacing invalid UTF-8.
+func previewText(data []byte) string {
+	return strings.ToValidUTF8(string(preview.TrimIncompleteRune(data)), "\uFFFD")
+}
+
+// markdownLinkResolver resolves relative links in a Markdown object in the folder to the routes of polybuckets:
+// folders to the listing, images to the download route and other files to the preview route.
+// Each path segment is escaped, so that keys with "#", "?" or spaces stay in the path of the link.
+func markdownLinkResolver(bucket, folder string) preview.LinkResolver {
+	return func(dest string, image bool) string {
+		// Keep the query and the fragment of the destination
+		suffix := ""
+		if i := strings.IndexAny(dest, "?#"); i >= 0 {
+			dest, suffix = dest[:i], dest[i:]
+		}
+		// Destinations may be percent-encoded, e.g. "a%23b.md" for the key "a#b.md"
+		if unescaped, err := url.PathUnescape(dest); err == nil {
+			dest = unescaped
+		}
+		target := escapeKey(strings.TrimPrefix(path.Join("/", folder, dest), "/"))
+		switch {
+		case strings.HasSuffix(dest, "/") || target == "":
+			return "/" + path.Join(bucket, target) + "/" + suffix
+		case image:
+			return "/download/" + bucket + "/" + target + suffix
+		default:
+			return "/-/preview/" + bucket + "/" + target + suffix
+		}
+	}
+}
+
+// escapeKey escapes each segment of an object key for a path of polybuckets.
+// The routes unescape keys with url.QueryUnescape, so "+" is escaped and spaces are escaped as "%20".
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	return strings.Join(segments, "/")
+}
+
+// readmeCacheMaxBytes is the maximum total size of the READMEs kept by readmeCache.
+const readmeCacheMaxBytes = 16 << 20
+
+// readmeCache keeps the rendered README.md of folders with the ETag of the object in the listing,
+// so that a listing served from the cache does not read its README from S3 again.
+type readmeCache = lru.Cache[renderedReadme]
+
+// renderedReadme is a README.md rendered from the version of the object with the ETag.
+type renderedReadme struct {
+	etag string
+	html template.HTML
+}
+
+// newReadmeCache creates an empty readmeCache bounded by readmeCacheMaxBytes.
+func newReadmeCache() *readmeCache {
+	return lru.New(0, readmeCacheMaxBytes, func(readme renderedReadme) int64 { return int64(len(readme.html)) })
+}
+
+// renderReadme renders the README.md among the objects of a listing, or returns "" if there is none.
+// The rendered README is cached with the ETag in the listing, so that it is read again when the listing
+// shows that it has changed.
+// A README that cannot be read is skipped so that the listing is still shown.
+func renderReadme(ctx context.Context, client *s3client.Client, readmes *readmeCache, bucket, prefix string, objects []s3client.ObjectInfo) template.HTML {
+	for _, obj := range objects {
+		if obj.IsDirectory || !strings.EqualFold(obj.ShortName, "README.md") {
+			continue
+		}
+		cacheKey := bucket + "/" + obj.Name
+		if readme, found := readmes.Get(cacheKey); found && readme.etag == obj.ETag {
+			return readme.html
+		}
+		chunk, err := client.ReadObjectRange(ctx, bucket, obj.Name, 0, env.PBConfig.PreviewMaxBytes)
+		if err != nil {
+			slog.Warn("failed to read README", "bucket", bucket, "key", obj.Name, "error", err)
+			return ""
+		}
+		readme, err := preview.RenderMarkdown(preview.TrimIncompleteRune(chunk.Data), markdownLinkResolver(bucket, prefix))
+		if err != nil {
+			slog.Warn("failed to render README", "bucket", bucket, "key", obj.Name, "error", err)
+			return ""
+		}
+		if obj.ETag != "" {
+			readmes.Set(cacheKey, renderedReadme{etag: obj.ETag, html: readme})
+		}
+		return readme
+	}
+	return ""
+}
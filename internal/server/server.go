@@ -8,13 +8,16 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/korosuke613/polybuckets/internal"
+	"github.com/korosuke613/polybuckets/internal/archive"
+	"github.com/korosuke613/polybuckets/internal/decompress"
 	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/preview"
 	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/korosuke613/polybuckets/internal/thumbnail"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -49,7 +52,7 @@ func SetupMiddleware(e *echo.Echo, templates embed.FS) {
 		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) {
 			writeString := ""
 
-			// if ListObject cache hit, output to log
+			// if ListBuckets or ListObjects cache hit, output to log
 			hitCache := c.Get("hitCache")
 			if hitCache != nil {
 				writeString += `,"hit_cache":` + strconv.FormatBool(hitCache.(bool))
@@ -59,6 +62,10 @@ func SetupMiddleware(e *echo.Echo, templates embed.FS) {
 					writeString += `,"cache_expire":"` + cacheExpire.(string) + `"`
 					c.Set("cacheExpire", nil)
 				}
+
+				if c.Get("cacheStale") == true {
+					writeString += `,"cache_stale":true`
+				}
 			}
 
 			return buf.WriteString(writeString)
@@ -75,12 +82,26 @@ func SetupMiddleware(e *echo.Echo, templates embed.FS) {
 // SetupRoutes sets up the routes for the Echo instance.
 func SetupRoutes(e *echo.Echo, ctx context.Context) {
 	// Initialize S3 client
-	siteName := env.PBConfig.SiteName
-	client, err := s3client.NewClient(ctx)
-	client.CacheDuration = env.PBConfig.CacheDuration
+	cache, err := s3client.NewListObjectsCache(env.PBConfig)
+	if err != nil {
+		e.Logger.Fatal("Failed to initialize cache: ", err)
+	}
+	client, err := s3client.NewClient(ctx, s3client.WithListObjectsCache(cache))
 	if err != nil {
 		e.Logger.Fatal("Failed to initialize S3 client: ", err)
 	}
+	client.CacheDuration = env.PBConfig.CacheDuration
+	client.StaleDuration = env.PBConfig.CacheStaleDuration
+	client.BucketsCacheDuration = env.PBConfig.BucketsCacheDuration
+	client.PageSize = env.PBConfig.PageSize
+
+	// Clear old cache entries in the background
+	client.StartCacheJanitor(ctx, s3client.DefaultCacheJanitorInterval)
+
+	thumbnails := thumbnail.NewCache(env.PBConfig.ThumbnailCacheMaxBytes)
+	readmes := newReadmeCache()
+	tars := newTarEntriesCache()
+	followers := newTailFollowers(env.PBConfig.TailMaxFollowers)
 
 	// Serve static files (favicon.ico)
 	e.Static("/static", "static")
@@ -93,40 +114,70 @@ func SetupRoutes(e *echo.Echo, ctx context.Context) {
 
 	// Route for file download
 	e.GET("/download/:bucket/*", func(c echo.Context) error {
-		bucket := c.Param("bucket")
-		key := c.Param("*")
+		return handleDownload(c, client)
+	})
 
-		// Unescape the key
-		key, err := url.QueryUnescape(key)
-		if err != nil {
-			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
-				"SiteName": siteName,
-				"Error":    err.Error(),
-			})
-		}
+	// The routes below are under "/-/" so that they do not hide buckets of the same name from the listing,
+	// since bucket names cannot start with a hyphen
 
-		// Get the object from S3
-		result, err := client.GetObject(c.Request().Context(), bucket, key)
-		if err != nil {
-			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
-				"SiteName": siteName,
-				"Error":    err.Error(),
-			})
-		}
-		defer result.Body.Close()
+	// Route for inline preview
+	e.GET("/-/preview/:bucket/*", func(c echo.Context) error {
+		return handlePreview(c, client)
+	})
+
+	// Route for the tail of log objects
+	e.GET("/-/tail/:bucket/*", func(c echo.Context) error {
+		return handleTail(c, client, followers)
+	})
+
+	// Route for the metadata and tags of an object
+	e.GET("/-/object/:bucket/*", func(c echo.Context) error {
+		return handleObjectDetail(c, client)
+	})
+
+	// Route for the versions and delete markers of an object or a folder
+	e.GET("/-/versions/:bucket/*", func(c echo.Context) error {
+		return handleVersions(c, client)
+	})
+
+	// Route for image thumbnails
+	e.GET("/-/thumbnail/:bucket/*", func(c echo.Context) error {
+		return handleThumbnail(c, client, thumbnails)
+	})
+
+	// Routes for browsing inside ZIP and tar archives and downloading their members
+	e.GET("/-/browse/:bucket/*", func(c echo.Context) error {
+		return handleBrowse(c, client, tars)
+	})
+	e.GET("/-/extract/:bucket/*", func(c echo.Context) error {
+		return handleExtract(c, client)
+	})
 
-		return c.Stream(http.StatusOK, "application/octet-stream", result.Body)
+	// Route for folder download as an archive
+	e.GET("/-/archive/:bucket/*", func(c echo.Context) error {
+		return handleArchive(c, client)
 	})
 
+	// Route for selected objects download as an archive
+	e.POST("/-/archive/:bucket", func(c echo.Context) error {
+		return handleArchiveSelection(c, client)
+	})
+
+	// Admin routes
+	setupAdminRoutes(e, client, env.PBConfig.AdminToken)
+
+	// S3 event notification webhook
+	setupWebhookRoutes(e, client, env.PBConfig.WebhookToken)
+
 	// Catch-all route handler
 	e.GET("/*", func(c echo.Context) error {
 		path := c.Request().URL.Path
-		return handleRequest(c.Request().Context(), c, client, path)
+		return handleRequest(c.Request().Context(), c, client, readmes, path)
 	})
 }
 
 // handleRequest handles incoming HTTP requests and routes them to the appropriate S3 operations.
-func handleRequest(ctx context.Context, c echo.Context, client *s3client.Client, path string) error {
+func handleRequest(ctx context.Context, c echo.Context, client *s3client.Client, readmes *readmeCache, path string) error {
 	siteName := env.PBConfig.SiteName
 	switch {
 	case path == "/":
@@ -134,14 +185,34 @@ func handleRequest(ctx context.Context, c echo.Context, client *s3client.Client,
 
 		// ListBuckets を継承
 		type BucketsInfo struct {
-			Buckets  []s3client.BucketInfo
-			SiteName string
+			Buckets    []s3client.BucketInfo
+			SiteName   string
+			HitCache   bool
+			LastCached time.Time
+		}
+
+		// if the query parameter `refresh` is set to `true`, clear the cache
+		if c.QueryParam("refresh") == "true" {
+			client.ClearListBucketsCache(ctx)
+		}
+
+		buckets, hitCache, err := client.ListBuckets(ctx)
+
+		c.Set("hitCache", hitCache)
+		var cacheExpire time.Time
+		if hitCache {
+			cacheEntry := client.GetListBucketsCacheEntry(ctx)
+			if cacheEntry != nil {
+				cacheExpire = cacheEntry.Expiry
+				c.Set("cacheExpire", cacheExpire.Format(time.RFC3339))
+			}
 		}
 
-		buckets, err := client.ListBuckets(ctx)
 		bucketsInfo := BucketsInfo{
-			Buckets:  buckets,
-			SiteName: siteName,
+			Buckets:    buckets,
+			SiteName:   siteName,
+			HitCache:   hitCache,
+			LastCached: cacheExpire.Add(-client.BucketsCacheDuration).UTC(),
 		}
 		if err != nil {
 			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
@@ -160,20 +231,23 @@ func handleRequest(ctx context.Context, c echo.Context, client *s3client.Client,
 			client.ClearListObjectsCache(ctx, bucket, prefix)
 		}
 
-		objects, hitCache, err := client.ListObjects(ctx, bucket, prefix)
+		// The page is given by its continuation token, which is empty for the first page,
+		// and the token of the page the link was on
+		token := c.QueryParam("token")
+		objectsPage, hitCache, err := client.ListObjects(ctx, bucket, prefix, token, c.QueryParam("prev"))
 
 		c.Set("hitCache", hitCache)
 		var cacheExpire time.Time
 		if hitCache {
-			cacheEntry := client.GetListObjectsCacheEntry(ctx, bucket, prefix)
+			cacheEntry := client.GetListObjectsCacheEntry(ctx, bucket, prefix, token)
 			if cacheEntry != nil {
 				cacheExpire = cacheEntry.Expiry
 				c.Set("cacheExpire", cacheExpire.Format(time.RFC3339))
 			}
 		}
-
-		// Clear old cache entries
-		go client.ClearOldListObjectsCache(ctx)
+		if objectsPage != nil && objectsPage.Refreshing {
+			c.Set("cacheStale", true)
+		}
 
 		if err != nil {
 			return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
@@ -190,13 +264,47 @@ func handleRequest(ctx context.Context, c echo.Context, client *s3client.Client,
 			"Bucket":       bucket,
 			"ParentPrefix": parentPrefix,
 			"Prefix":       prefix,
-			"Objects":      objects,
+			"Objects":      listingItems(objectsPage.Objects),
+			"Gallery":      c.QueryParam("view") == "gallery",
+			"Readme":       renderReadme(ctx, client, readmes, bucket, prefix, objectsPage.Objects),
+			"Page":         objectsPage.Page,
+			"Token":        objectsPage.Token,
+			"HasPrev":      objectsPage.HasPrev,
+			"PrevToken":    objectsPage.PrevToken,
+			"NextToken":    objectsPage.NextToken,
 			"HitCache":     hitCache,
+			"Refreshing":   objectsPage.Refreshing,
 			"LastCached":   cacheExpire.Add(-client.CacheDuration).UTC(),
 		})
 	}
 }
 
+// listingItem is an object in objects.html with how it is previewed.
+type listingItem struct {
+	s3client.ObjectInfo
+	Kind      preview.Kind
+	Thumbnail bool
+	// Browsable is true for ZIP and tar archives, which can be browsed like folders.
+	Browsable bool
+	// Compressed is true for gzip, zstd and bzip2 compressed objects, which can be downloaded decompressed.
+	Compressed bool
+}
+
+// listingItems adds the preview information to the objects of a listing.
+func listingItems(objects []s3client.ObjectInfo) []listingItem {
+	items := make([]listingItem, len(objects))
+	for i, obj := range objects {
+		items[i] = listingItem{ObjectInfo: obj}
+		if !obj.IsDirectory {
+			items[i].Kind = preview.KindByExtension(obj.Name)
+			items[i].Thumbnail = thumbnail.Supported(obj.Name)
+			items[i].Browsable = archive.IsZip(obj.Name) || archive.IsTar(obj.Name)
+			items[i].Compressed = decompress.ByExtension(obj.Name) != "" && !archive.IsTar(obj.Name)
+		}
+	}
+	return items
+}
+
 // StartServer starts the Echo server with the provided configuration.
 func StartServer(e *echo.Echo, pbConfig *env.PBConfigType) {
 	port := pbConfig.Port
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// testModTime is the modification time of the objects of stubS3Client.
+var testModTime = time.Date(2025, 1, 26, 2, 5, 16, 0, time.UTC)
+
+// stubObject is an object of stubS3Client. If err is set, GetObject fails with it.
+type stubObject struct {
+	data        string
+	etag        string
+	contentType string
+	err         error
+}
+
+// stubS3Client implements the S3Client interface with objects in memory, keyed by "bucket/key".
+// GetObject supports a single range and the If-None-Match and If-Modified-Since conditions like S3.
+type stubS3Client struct {
+	objects map[string]stubObject
+}
+
+// stubErrorCodes are the S3 error codes of the HTTP status codes returned by stubS3Client.
+var stubErrorCodes = map[int]string{
+	http.StatusNotModified:                  "NotModified",
+	http.StatusForbidden:                    "AccessDenied",
+	http.StatusNotFound:                     "NoSuchKey",
+	http.StatusRequestedRangeNotSatisfiable: "InvalidRange",
+}
+
+// statusError returns an error of an S3 response with the HTTP status code.
+func statusError(statusCode int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+			Err:      &smithy.GenericAPIError{Code: stubErrorCodes[statusCode], Message: fmt.Sprintf("status %d", statusCode)},
+		},
+	}
+}
+
+// object returns the object, or a 404 error if it does not exist.
+func (s *stubS3Client) object(bucket, key *string) (stubObject, error) {
+	obj, found := s.objects[aws.ToString(bucket)+"/"+aws.ToString(key)]
+	if !found {
+		return stubObject{}, statusError(http.StatusNotFound)
+	}
+	return obj, nil
+}
+
+// ListBuckets returns no buckets.
+func (s *stubS3Client) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{}, nil
+}
+
+// ListObjectsV2 lists all objects of the bucket under the prefix in a single page.
+func (s *stubS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	bucketPrefix := aws.ToString(params.Bucket) + "/"
+	var keys []string
+	for name := range s.objects {
+		if key, ok := strings.CutPrefix(name, bucketPrefix); ok && strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	output := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	for _, key := range keys {
+		obj := s.objects[bucketPrefix+key]
+		output.Contents = append(output.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(int64(len(obj.data))),
+			ETag:         aws.String(obj.etag),
+			LastModified: aws.Time(testModTime),
+		})
+	}
+	return output, nil
+}
+
+// GetObject returns the object or the requested range of it.
+func (s *stubS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	obj, err := s.object(params.Bucket, params.Key)
+	if err != nil {
+		return nil, err
+	}
+	if obj.err != nil {
+		return nil, obj.err
+	}
+	if params.IfNoneMatch != nil && *params.IfNoneMatch == obj.etag {
+		return nil, statusError(http.StatusNotModified)
+	}
+	if params.IfNoneMatch == nil && params.IfModifiedSince != nil && !testModTime.After(*params.IfModifiedSince) {
+		return nil, statusError(http.StatusNotModified)
+	}
+
+	output := &s3.GetObjectOutput{
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(testModTime),
+		ContentLength: aws.Int64(int64(len(obj.data))),
+	}
+	if obj.contentType != "" {
+		output.ContentType = aws.String(obj.contentType)
+	}
+	data := obj.data
+	if params.Range != nil {
+		size := int64(len(obj.data))
+		startString, endString, _ := strings.Cut(strings.TrimPrefix(*params.Range, "bytes="), "-")
+		start, _ := strconv.ParseInt(startString, 10, 64)
+		end, err := strconv.ParseInt(endString, 10, 64)
+		switch {
+		case startString == "":
+			start, end = max(size-end, 0), size-1
+		case err != nil:
+			end = size - 1
+		}
+		if start >= size {
+			return nil, statusError(http.StatusRequestedRangeNotSatisfiable)
+		}
+		end = min(end, size-1)
+		data = obj.data[start : end+1]
+		output.ContentLength = aws.Int64(int64(len(data)))
+		output.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+	output.Body = io.NopCloser(strings.NewReader(data))
+	return output, nil
+}
+
+// HeadObject returns the size and the ETag of the object.
+func (s *stubS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	obj, err := s.object(params.Bucket, params.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectOutput{
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(testModTime),
+		ContentLength: aws.Int64(int64(len(obj.data))),
+	}, nil
+}
+
+// GetObjectTagging returns no tags.
+func (s *stubS3Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return &s3.GetObjectTaggingOutput{}, nil
+}
+
+// ListObjectVersions returns no versions.
+func (s *stubS3Client) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{}, nil
+}
+
+// newTestClient returns a client of a stubS3Client with the objects.
+func newTestClient(t *testing.T, objects map[string]stubObject) *s3client.Client {
+	client, err := s3client.NewClient(context.Background(), s3client.WithCustomClient(&stubS3Client{objects: objects}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.CacheDuration = time.Minute
+	return client
+}
+
+// newTestEcho returns an Echo instance that renders the templates of the repository.
+func newTestEcho(t *testing.T) *echo.Echo {
+	templates, err := template.ParseFS(os.DirFS("../.."), "templates/*.html", "templates/partials/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Renderer = &TemplateRenderer{templates: templates}
+	return e
+}
+
+// withConfig replaces the configuration for the duration of the test.
+func withConfig(t *testing.T, modify func(config *env.PBConfigType)) {
+	saved := env.PBConfig
+	config := *saved
+	modify(&config)
+	env.PBConfig = &config
+	t.Cleanup(func() { env.PBConfig = saved })
+}
+
+// serve sends the request to the Echo instance and returns the response.
+func serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
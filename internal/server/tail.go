@@ -0,0 +1,271 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/preview"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultTailBytes is the default number of bytes shown in the tail view.
+const defaultTailBytes = 64 << 10
+
+// Limits of following an object that cannot be read.
+const (
+	// maxTailErrors is the number of failed polls in a row after which following ends.
+	maxTailErrors = 10
+	// maxTailPollBackoff is the maximum interval of polling an object that cannot be read.
+	maxTailPollBackoff = 5 * time.Minute
+)
+
+// tailFollowers limits the number of objects followed at the same time, since each follower polls S3.
+// A nil tailFollowers has no limit.
+type tailFollowers chan struct{}
+
+// newTailFollowers creates a tailFollowers of at most maxFollowers, or no limit if it is not positive.
+func newTailFollowers(maxFollowers int) tailFollowers {
+	if maxFollowers <= 0 {
+		return nil
+	}
+	return make(tailFollowers, maxFollowers)
+}
+
+// acquire reserves a follower, or reports false if the limit has been reached.
+func (f tailFollowers) acquire() bool {
+	if f == nil {
+		return true
+	}
+	select {
+	case f <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a follower reserved by acquire.
+func (f tailFollowers) release() {
+	if f != nil {
+		<-f
+	}
+}
+
+// tailPosition is the end of the part of an object that has been sent to the browser.
+type tailPosition struct {
+	offset int64
+	etag   string
+}
+
+// eventID returns the position as the ID of a Server-Sent Event, which the browser sends back on reconnection.
+func (p *tailPosition) eventID() string {
+	return strconv.FormatInt(p.offset, 10) + " " + p.etag
+}
+
+// parseTailPosition parses the position from an event ID, or returns nil if it is invalid.
+func parseTailPosition(id string) *tailPosition {
+	offsetString, etag, _ := strings.Cut(id, " ")
+	offset, err := strconv.ParseInt(offsetString, 10, 64)
+	if err != nil || offset < 0 {
+		return nil
+	}
+	return &tailPosition{offset: offset, etag: etag}
+}
+
+// handleTail shows the last kilobytes of an object in tail.html, given by the kb query parameter.
+// Requests from an EventSource follow the object and push its growth as Server-Sent Events.
+func handleTail(c echo.Context, client *s3client.Client, followers tailFollowers) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	prefix := strings.TrimSuffix(path.Dir(key), ".")
+
+	// The tail is at most as large as a preview
+	tailBytes := int64(defaultTailBytes)
+	if kb, err := strconv.ParseInt(c.QueryParam("kb"), 10, 64); err == nil && kb > 0 {
+		// Clamp before shifting so that large values do not overflow
+		tailBytes = min(kb, env.PBConfig.PreviewMaxBytes>>10+1) << 10
+	}
+	tailBytes = min(tailBytes, env.PBConfig.PreviewMaxBytes)
+
+	if c.Request().Header.Get(echo.HeaderAccept) == "text/event-stream" {
+		return followTail(c, client, followers, bucket, key, tailBytes)
+	}
+
+	chunk, err := client.ReadObjectRange(ctx, bucket, key, -1, tailBytes)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if httpStatusCode(err) == http.StatusNotFound {
+			status = http.StatusNotFound
+		}
+		return c.Render(status, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   prefix,
+		})
+	}
+	text, position := tailText(chunk)
+
+	return c.Render(http.StatusOK, "tail.html", map[string]interface{}{
+		"SiteName":  siteName,
+		"Bucket":    bucket,
+		"Prefix":    prefix,
+		"Key":       key,
+		"Name":      path.Base(key),
+		"Size":      s3client.FormatSize(chunk.Size),
+		"TailSize":  s3client.FormatSize(tailBytes),
+		"Content":   text,
+		"Position":  position.eventID(),
+		"Interval":  env.PBConfig.TailPollInterval.String(),
+		"Truncated": chunk.Offset > 0,
+	})
+}
+
+// followTail polls the object every PB_TAIL_POLL_INTERVAL and pushes its changes as Server-Sent Events
+// until the browser disconnects:
+// "append" with the bytes appended to the object, "reset" with its tail when it has been rotated or rewritten,
+// and "warning" when it cannot be read. The data of the events are JSON strings.
+// The interval is doubled while the object cannot be read, and an "end" event ends following after
+// maxTailErrors failures in a row.
+// Polling starts from the position in the Last-Event-ID header or the position query parameter.
+func followTail(c echo.Context, client *s3client.Client, followers tailFollowers, bucket, key string, tailBytes int64) error {
+	ctx := c.Request().Context()
+	position := parseTailPosition(c.Request().Header.Get("Last-Event-ID"))
+	if position == nil {
+		position = parseTailPosition(c.QueryParam("position"))
+	}
+	if position == nil {
+		return c.String(http.StatusBadRequest, "invalid position")
+	}
+	if !followers.acquire() {
+		return c.String(http.StatusServiceUnavailable, "too many objects are followed")
+	}
+	defer followers.release()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	interval := env.PBConfig.TailPollInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	errorCount := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+
+		event, text, err := pollTail(ctx, client, bucket, key, position, tailBytes)
+		if ctx.Err() != nil {
+			return nil
+		}
+		delay := interval
+		switch {
+		case err == nil:
+			errorCount = 0
+		case errorCount+1 == maxTailErrors:
+			event, text = "end", fmt.Sprintf("stopped following after %d failures: %v", maxTailErrors, err)
+		default:
+			errorCount++
+			event, text = "warning", err.Error()
+			// Back off while the object cannot be read, e.g. while it is missing
+			delay = min(interval<<errorCount, max(interval, maxTailPollBackoff))
+		}
+		if event == "" {
+			// A comment keeps the connection alive through proxies
+			_, err = fmt.Fprint(res, ": unchanged\n\n")
+		} else {
+			data, _ := json.Marshal(text)
+			_, err = fmt.Fprintf(res, "event: %s\nid: %s\ndata: %s\n\n", event, position.eventID(), data)
+		}
+		if err != nil || event == "end" {
+			return nil
+		}
+		res.Flush()
+		timer.Reset(delay)
+	}
+}
+
+// pollTail checks the object for changes after the position and advances the position.
+// It returns the name and the text of the event to push, or an empty name if the object has not changed.
+// An object is assumed to have been appended to while it grows.
+func pollTail(ctx context.Context, client *s3client.Client, bucket, key string, position *tailPosition, tailBytes int64) (string, string, error) {
+	chunk, err := client.ReadObjectRange(ctx, bucket, key, position.offset, tailBytes, s3client.WithIfNoneMatch(position.etag))
+	switch httpStatusCode(err) {
+	case http.StatusNotModified:
+		return "", "", nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Nothing has been appended, but the object may have been rewritten with the same or a smaller size
+		chunk, err = client.ReadObjectRange(ctx, bucket, key, -1, tailBytes)
+		if err != nil {
+			return "", "", err
+		}
+		if chunk.ETag == position.etag {
+			return "", "", nil
+		}
+		text, newPosition := tailText(chunk)
+		*position = newPosition
+		return "reset", text, nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case chunk.ETag == position.etag:
+		// Only a character cut off at the end of the last event is left, which is sent with the next append
+		return "", "", nil
+	case chunk.Size > position.offset+tailBytes:
+		// More has been appended than the tail, so the skipped part is not shown
+		chunk, err = client.ReadObjectRange(ctx, bucket, key, -1, tailBytes)
+		if err != nil {
+			return "", "", err
+		}
+		text, newPosition := tailText(chunk)
+		*position = newPosition
+		return "reset", text, nil
+	}
+
+	// A character cut off at the end is sent with the next append
+	data := preview.TrimIncompleteRune(chunk.Data)
+	position.offset += int64(len(data))
+	position.etag = chunk.ETag
+	return "append", strings.ToValidUTF8(string(data), "\uFFFD"), nil
+}
+
+// tailText returns the text of the tail of an object without the line cut off at the beginning,
+// and the position of the end of the text.
+func tailText(chunk *s3client.ObjectChunk) (string, tailPosition) {
+	data := preview.TrimIncompleteRune(chunk.Data)
+	position := tailPosition{offset: chunk.Offset + int64(len(data)), etag: chunk.ETag}
+	if chunk.Offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return strings.ToValidUTF8(string(data), "\uFFFD"), position
+}
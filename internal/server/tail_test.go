@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPollTail tests the events sent for the changes of a followed object
+func TestPollTail(t *testing.T) {
+	tests := []struct {
+		name             string
+		object           stubObject
+		tailBytes        int64
+		expectedEvent    string
+		expectedText     string
+		expectedPosition tailPosition
+	}{
+		{
+			name:             "正常系: 変更なし",
+			object:           stubObject{data: "0123456789", etag: `"v1"`},
+			expectedPosition: tailPosition{offset: 10, etag: `"v1"`},
+		},
+		{
+			name:             "正常系: 追記",
+			object:           stubObject{data: "0123456789abc\n", etag: `"v2"`},
+			expectedEvent:    "append",
+			expectedText:     "abc\n",
+			expectedPosition: tailPosition{offset: 14, etag: `"v2"`},
+		},
+		{
+			name:             "正常系: 末尾で途切れた文字は次の追記で送る",
+			object:           stubObject{data: "0123456789x\xe3\x81", etag: `"v2"`},
+			expectedEvent:    "append",
+			expectedText:     "x",
+			expectedPosition: tailPosition{offset: 11, etag: `"v2"`},
+		},
+		{
+			name:             "正常系: 縮小",
+			object:           stubObject{data: "01234", etag: `"v2"`},
+			expectedEvent:    "reset",
+			expectedText:     "01234",
+			expectedPosition: tailPosition{offset: 5, etag: `"v2"`},
+		},
+		{
+			name:             "正常系: 同じサイズで書き換え",
+			object:           stubObject{data: "abcdefghij", etag: `"v2"`},
+			expectedEvent:    "reset",
+			expectedText:     "abcdefghij",
+			expectedPosition: tailPosition{offset: 10, etag: `"v2"`},
+		},
+		{
+			name:             "正常系: 末尾のサイズを超える追記",
+			object:           stubObject{data: "0123456789line1\nline2\n", etag: `"v2"`},
+			tailBytes:        8,
+			expectedEvent:    "reset",
+			expectedText:     "line2\n",
+			expectedPosition: tailPosition{offset: 22, etag: `"v2"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]stubObject{"bucket1/app.log": tt.object})
+			tailBytes := tt.tailBytes
+			if tailBytes == 0 {
+				tailBytes = 1024
+			}
+			position := tailPosition{offset: 10, etag: `"v1"`}
+
+			event, text, err := pollTail(context.Background(), client, "bucket1", "app.log", &position, tailBytes)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEvent, event)
+			assert.Equal(t, tt.expectedText, text)
+			assert.Equal(t, tt.expectedPosition, position)
+		})
+	}
+}
+
+// TestPollTail_NotFound tests that a deleted object is reported as an error
+func TestPollTail_NotFound(t *testing.T) {
+	client := newTestClient(t, map[string]stubObject{})
+	position := tailPosition{offset: 10, etag: `"v1"`}
+
+	_, _, err := pollTail(context.Background(), client, "bucket1", "app.log", &position, 1024)
+
+	assert.Error(t, err)
+	assert.Equal(t, tailPosition{offset: 10, etag: `"v1"`}, position)
+}
+
+// TestFollowTail tests the Server-Sent Events of following an object
+func TestFollowTail(t *testing.T) {
+	withConfig(t, func(config *env.PBConfigType) {
+		config.TailPollInterval = 100 * time.Microsecond
+	})
+	client := newTestClient(t, map[string]stubObject{})
+	followers := newTailFollowers(1)
+	e := newTestEcho(t)
+	e.GET("/-/tail/:bucket/*", func(c echo.Context) error {
+		return handleTail(c, client, followers)
+	})
+	follow := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAccept, "text/event-stream")
+		return serve(e, req)
+	}
+
+	t.Run("異常系: 不正な位置", func(t *testing.T) {
+		rec := follow("/-/tail/bucket1/app.log?position=invalid")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("異常系: 読めないオブジェクトは警告の後に終了", func(t *testing.T) {
+		rec := follow("/-/tail/bucket1/app.log?position=10+%22v1%22")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+		body := rec.Body.String()
+		assert.Equal(t, maxTailErrors-1, strings.Count(body, "event: warning\n"))
+		assert.True(t, strings.HasPrefix(body[strings.LastIndex(body, "event: "):], "event: end\n"))
+
+		// The follower is released when following ends
+		assert.True(t, followers.acquire())
+		followers.release()
+	})
+
+	t.Run("異常系: フォロー数の上限", func(t *testing.T) {
+		followers.acquire()
+		defer followers.release()
+		rec := follow("/-/tail/bucket1/app.log?position=10+%22v1%22")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+// TestTailFollowers tests that followers are limited until they are released
+func TestTailFollowers(t *testing.T) {
+	followers := newTailFollowers(2)
+	assert.True(t, followers.acquire())
+	assert.True(t, followers.acquire())
+	assert.False(t, followers.acquire())
+
+	followers.release()
+	assert.True(t, followers.acquire())
+
+	// No limit if it is not positive
+	unlimited := newTailFollowers(0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.acquire())
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/korosuke613/polybuckets/internal/thumbnail"
+	"github.com/labstack/echo/v4"
+)
+
+// handleThumbnail responds with a JPEG thumbnail of an image object.
+// Thumbnails are cached with the object's ETag and revalidated against S3 with If-None-Match,
+// so that an unchanged image is not downloaded again.
+func handleThumbnail(c echo.Context, client *s3client.Client, cache *thumbnail.Cache) error {
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if !thumbnail.Supported(key) {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	cacheKey := bucket + "/" + key
+	etag, data, found := cache.Get(cacheKey)
+	var opts []s3client.GetObjectOption
+	if found {
+		opts = append(opts, s3client.WithIfNoneMatch(etag))
+	}
+
+	result, err := client.GetObject(ctx, bucket, key, opts...)
+	switch {
+	case err != nil && found && httpStatusCode(err) == http.StatusNotModified:
+		// The cached thumbnail is up to date
+	case err != nil:
+		slog.Warn("failed to get image for thumbnail", "bucket", bucket, "key", key, "error", err)
+		if httpStatusCode(err) == http.StatusNotFound {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusBadGateway)
+	default:
+		defer result.Body.Close()
+		if maxBytes := env.PBConfig.ThumbnailMaxBytes; maxBytes > 0 && aws.ToInt64(result.ContentLength) > maxBytes {
+			return c.NoContent(http.StatusUnprocessableEntity)
+		}
+		etag = aws.ToString(result.ETag)
+		data, err = thumbnail.Generate(result.Body, thumbnail.DefaultSize)
+		if err != nil {
+			slog.Warn("failed to generate thumbnail", "bucket", bucket, "key", key, "error", err)
+			return c.NoContent(http.StatusUnprocessableEntity)
+		}
+		cache.Set(cacheKey, etag, data)
+	}
+
+	header := c.Response().Header()
+	if cacheControl := env.PBConfig.CacheControlFor(bucket); cacheControl != "" {
+		header.Set(echo.HeaderCacheControl, cacheControl)
+	}
+	if etag != "" {
+		header.Set("ETag", etag)
+		if c.Request().Header.Get("If-None-Match") == etag {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+	return c.Blob(http.StatusOK, "image/jpeg", data)
+}
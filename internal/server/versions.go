@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/korosuke613/polybuckets/internal/env"
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/labstack/echo/v4"
+)
+
+// versionItem is a version or a delete marker in versions.html.
+type versionItem struct {
+	s3client.ObjectVersion
+	// ShortName is the key relative to the listed folder.
+	ShortName     string
+	FormattedSize string
+}
+
+// handleVersions lists the versions and delete markers of an object, or of all objects in a folder if the path
+// is empty or ends with a slash, in versions.html. The listing continues from the key-marker and
+// version-id-marker query parameters, which are given in the link to the next page.
+func handleVersions(c echo.Context, client *s3client.Client) error {
+	siteName := env.PBConfig.SiteName
+	ctx := c.Request().Context()
+	bucket := c.Param("bucket")
+
+	// Unescape the key or the prefix
+	key, err := url.QueryUnescape(c.Param("*"))
+	if err != nil {
+		return c.Render(http.StatusBadRequest, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+		})
+	}
+	isFolder := key == "" || strings.HasSuffix(key, "/")
+	folder := key
+	if !isFolder {
+		folder = strings.TrimSuffix(path.Dir(key), ".")
+		if folder != "" {
+			folder += "/"
+		}
+	}
+
+	page, err := client.ListObjectVersions(ctx, bucket, key, c.QueryParam("key-marker"), c.QueryParam("version-id-marker"))
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error.html", map[string]interface{}{
+			"SiteName": siteName,
+			"Error":    err.Error(),
+			"Bucket":   bucket,
+			"Prefix":   strings.TrimSuffix(folder, "/"),
+		})
+	}
+
+	items := make([]versionItem, 0, len(page.Versions))
+	truncated := page.Truncated
+	for _, version := range page.Versions {
+		// The prefix of an object also matches the keys that start with it, which are listed after the object
+		if !isFolder && version.Key != key {
+			truncated = false
+			break
+		}
+		item := versionItem{ObjectVersion: version, ShortName: strings.TrimPrefix(version.Key, folder)}
+		if !version.IsDeleteMarker {
+			item.FormattedSize = s3client.FormatSize(version.Size)
+		}
+		items = append(items, item)
+	}
+
+	return c.Render(http.StatusOK, "versions.html", map[string]interface{}{
+		"SiteName":            siteName,
+		"Bucket":              bucket,
+		"Folder":              folder,
+		"Key":                 key,
+		"Name":                path.Base(key),
+		"IsFolder":            isFolder,
+		"Versions":            items,
+		"FirstPage":           c.QueryParam("key-marker") == "",
+		"Truncated":           truncated,
+		"NextKeyMarker":       page.NextKeyMarker,
+		"NextVersionIDMarker": page.NextVersionIDMarker,
+	})
+}
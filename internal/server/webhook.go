@@ -0,0 +1,34 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/korosuke613/polybuckets/internal/s3client"
+	"github.com/korosuke613/polybuckets/internal/s3event"
+	"github.com/labstack/echo/v4"
+)
+
+// setupWebhookRoutes sets up the S3 event notification webhook authenticated by the bearer token.
+// The route is not registered when the token is empty.
+func setupWebhookRoutes(e *echo.Echo, client *s3client.Client, token string) {
+	if token == "" {
+		return
+	}
+
+	// Invalidate the listObjects cache of created or removed objects
+	e.POST("/-/webhook/s3-events", func(c echo.Context) error {
+		changes, err := s3event.Parse(c.Request().Body)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		ctx := c.Request().Context()
+		for _, change := range changes {
+			client.InvalidateObject(ctx, change.Bucket, change.Key)
+		}
+		slog.Info("invalidated cache by S3 event notification", "objects", len(changes))
+
+		return c.NoContent(http.StatusNoContent)
+	}, bearerAuth(token))
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWebhookRoutes tests the authentication of the webhook and the invalidation of the listings of changed objects
+func TestWebhookRoutes(t *testing.T) {
+	event := `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket1"},"object":{"key":"dir/new.txt"}}}]}`
+
+	tests := []struct {
+		name            string
+		authorization   string
+		body            string
+		expectedStatus  int
+		expectedEntries []string
+	}{
+		{name: "異常系: トークンなし", body: event, expectedStatus: http.StatusUnauthorized, expectedEntries: []string{"bucket1/?token=", "bucket1/dir?token=", "bucket2/?token="}},
+		{name: "異常系: トークンが不正", authorization: "Bearer wrong", body: event, expectedStatus: http.StatusUnauthorized, expectedEntries: []string{"bucket1/?token=", "bucket1/dir?token=", "bucket2/?token="}},
+		{name: "異常系: 不正なイベント", authorization: "Bearer secret", body: "not json", expectedStatus: http.StatusBadRequest, expectedEntries: []string{"bucket1/?token=", "bucket1/dir?token=", "bucket2/?token="}},
+		{name: "正常系: 祖先のプレフィックスを無効化", authorization: "Bearer secret", body: event, expectedStatus: http.StatusNoContent, expectedEntries: []string{"bucket2/?token="}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t, map[string]stubObject{
+				"bucket1/dir/a.txt": {data: "a"},
+				"bucket2/b.txt":     {data: "b"},
+			})
+			for _, listing := range [][2]string{{"bucket1", ""}, {"bucket1", "dir"}, {"bucket2", ""}} {
+				_, _, err := client.ListObjects(ctx, listing[0], listing[1], "", "")
+				assert.NoError(t, err)
+			}
+			e := newTestEcho(t)
+			setupWebhookRoutes(e, client, "secret")
+
+			req := httptest.NewRequest(http.MethodPost, "/-/webhook/s3-events", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := serve(e, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			var keys []string
+			for _, entry := range client.ListObjectsCacheEntries(ctx) {
+				keys = append(keys, entry.Key)
+			}
+			assert.ElementsMatch(t, tt.expectedEntries, keys)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package thumbnail
+
+import (
+	"github.com/korosuke613/polybuckets/internal/lru"
+)
+
+// Cache is an in-memory LRU cache of thumbnails bounded by their total bytes.
+// Each thumbnail is stored with the ETag of the object it was generated from.
+type Cache struct {
+	items *lru.Cache[cacheItem]
+}
+
+// cacheItem is a thumbnail and the ETag of the object it was generated from.
+type cacheItem struct {
+	etag string
+	data []byte
+}
+
+// NewCache creates an empty thumbnail cache. A maxBytes of 0 or less disables the bound.
+func NewCache(maxBytes int64) *Cache {
+	return &Cache{
+		items: lru.New(0, maxBytes, func(item cacheItem) int64 { return int64(len(item.data)) }),
+	}
+}
+
+// Get returns the thumbnail and the object ETag for the specified key and marks it as recently used.
+func (c *Cache) Get(key string) (etag string, data []byte, found bool) {
+	item, found := c.items.Get(key)
+	return item.etag, item.data, found
+}
+
+// Set stores the thumbnail for the specified key and evicts thumbnails until the bound is satisfied.
+func (c *Cache) Set(key, etag string, data []byte) {
+	c.items.Set(key, cacheItem{etag: etag, data: data})
+}
+
+// Len returns the number of thumbnails in the cache.
+func (c *Cache) Len() int {
+	return c.items.Len()
+}
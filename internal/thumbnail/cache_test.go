@@ -0,0 +1,31 @@
+package thumbnail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCache tests storing and evicting thumbnails
+func TestCache(t *testing.T) {
+	cache := NewCache(10)
+	cache.Set("a", `"etag-a"`, []byte("aaaa"))
+	cache.Set("b", `"etag-b"`, []byte("bbbb"))
+
+	// "a" becomes the most recently used
+	etag, data, found := cache.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, `"etag-a"`, etag)
+	assert.Equal(t, []byte("aaaa"), data)
+
+	// "b" is evicted to keep the total within 10 bytes
+	cache.Set("c", `"etag-c"`, []byte("cccc"))
+	_, _, found = cache.Get("b")
+	assert.False(t, found)
+	assert.Equal(t, 2, cache.Len())
+
+	// Replacing a thumbnail updates its ETag
+	cache.Set("a", `"etag-a2"`, []byte("a"))
+	etag, _, _ = cache.Get("a")
+	assert.Equal(t, `"etag-a2"`, etag)
+}
@@ -0,0 +1,80 @@
+// Package thumbnail generates and caches thumbnails of images stored in buckets.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// DefaultSize is the default maximum width and height of thumbnails in pixels.
+const DefaultSize = 256
+
+// maxPixels limits the pixels of a decoded image to protect the server from decompression bombs.
+const maxPixels = 50_000_000
+
+// ErrTooLarge is returned when the image has too many pixels to make a thumbnail.
+var ErrTooLarge = errors.New("image is too large to make a thumbnail")
+
+// supportedExtensions are the extensions of images that thumbnails can be generated from.
+var supportedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+}
+
+// Supported reports whether a thumbnail can be generated for the object key.
+func Supported(key string) bool {
+	return supportedExtensions[strings.ToLower(path.Ext(key))]
+}
+
+// Generate decodes a PNG, JPEG, GIF or WebP image and returns a JPEG thumbnail that fits in size x size pixels.
+// Only the first frame of an animated GIF is used, and transparent pixels are drawn on white.
+func Generate(r io.Reader, size int) ([]byte, error) {
+	// Check the dimensions before decoding the whole image
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), size)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// fit returns the dimensions scaled down to fit in size x size, keeping the aspect ratio.
+// Images smaller than size are not scaled up.
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(height*size/width, 1)
+	}
+	return max(width*size/height, 1), size
+}
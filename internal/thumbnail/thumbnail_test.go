@@ -0,0 +1,76 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerate tests generating thumbnails from encoded images
+func TestGenerate(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var pngImage, gifImage bytes.Buffer
+	assert.NoError(t, png.Encode(&pngImage, src))
+	assert.NoError(t, gif.Encode(&gifImage, src, nil))
+
+	tests := []struct {
+		name           string
+		input          []byte
+		size           int
+		expectedWidth  int
+		expectedHeight int
+		expectedErr    string
+	}{
+		{name: "正常系: PNGを縮小", input: pngImage.Bytes(), size: 100, expectedWidth: 100, expectedHeight: 50},
+		{name: "正常系: GIFを縮小", input: gifImage.Bytes(), size: 100, expectedWidth: 100, expectedHeight: 50},
+		{name: "正常系: 小さい画像は拡大しない", input: pngImage.Bytes(), size: 1000, expectedWidth: 400, expectedHeight: 200},
+		{name: "異常系: 画像ではない", input: []byte("not an image"), size: 100, expectedErr: "failed to decode image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Generate(bytes.NewReader(tt.input), tt.size)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			thumbnail, err := jpeg.Decode(bytes.NewReader(result))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedWidth, thumbnail.Bounds().Dx())
+			assert.Equal(t, tt.expectedHeight, thumbnail.Bounds().Dy())
+		})
+	}
+}
+
+// TestGenerate_TooLarge tests that images with too many pixels are rejected before decoding
+func TestGenerate_TooLarge(t *testing.T) {
+	// A GIF whose logical screen claims 10000x10000 pixels
+	var buf bytes.Buffer
+	assert.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil))
+	header := buf.Bytes()
+	copy(header[6:10], []byte{0x10, 0x27, 0x10, 0x27})
+
+	_, err := Generate(bytes.NewReader(header), DefaultSize)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+// TestSupported tests detecting images that thumbnails can be generated from
+func TestSupported(t *testing.T) {
+	assert.True(t, Supported("screenshots/a.PNG"))
+	assert.True(t, Supported("b.webp"))
+	assert.False(t, Supported("c.svg"))
+	assert.False(t, Supported("Makefile"))
+}